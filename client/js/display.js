@@ -0,0 +1,103 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+// big screen display of a game, it watches the game without joining it, and never receives any names
+var app = new Vue({
+    el: "#display",
+    data: {
+        socket: null,
+        game: null,
+        code: "",
+        error: null,
+        passphrase: "",
+        origin: window.location.origin,
+        now: Date.now(),
+    },
+    computed: {
+        roundRule: function () {
+            if (!this.game) { return ""; }
+            switch (this.game.round) {
+                case 1:
+                    return "Say Anything";
+                case 2:
+                    return "Silent Clues Only";
+                case 3:
+                    return "One Word Only";
+            }
+            return "";
+        },
+        stealingTeam: function () {
+            if (!this.game || !this.game.clueGiver) { return null; }
+            if (this.game.team1.players.find(player => player.name === this.game.clueGiver.name)) {
+                return 2;
+            }
+            return 1;
+        },
+        recentTurns: function () {
+            if (!this.game || !this.game.turnSummaries) { return []; }
+            return this.game.turnSummaries.slice(-5).reverse();
+        },
+        timeLeft: function () {
+            return timerLeft(this.game && this.game.timer, this.now);
+        },
+        timerPercent: function () {
+            if (this.game && this.game.timer && this.game.timer.seconds) {
+                return (this.timeLeft / this.game.timer.seconds) * 100;
+            }
+            return 0;
+        },
+        timerStyle: function () {
+            if (this.game && this.game.timer && this.game.timer.seconds) {
+                let ratio = this.timeLeft / this.game.timer.seconds;
+                if (ratio < .25) {
+                    return "danger";
+                }
+                if (ratio < .5) {
+                    return "warning";
+                }
+            }
+            return "success";
+        },
+    },
+    methods: {
+        receive: function (msg) {
+            switch (msg.type) {
+                case "state":
+                    this.error = null;
+                    this.game = msg.data;
+                    break;
+                case "patch":
+                    let state = applyPatch(this.game, msg.data);
+                    if (!state) {
+                        this.socket.send({ type: "requestupdate" });
+                        break;
+                    }
+                    this.game = state;
+                    break;
+                case "error":
+                    this.error = msg.data.message;
+                    break;
+            }
+        },
+        watch: function () {
+            this.socket.passphrase = this.passphrase;
+            this.socket.connect()
+                .catch((err) => {
+                    this.error = "Error connecting to the game server: " + err;
+                });
+        },
+    },
+    mounted: function () {
+        this.code = document.getElementById("display").getAttribute("data-code");
+        this.socket = ViewerSocket(this.code, "display", this.receive);
+        // timers count down locally to the deadline sent by the server
+        setInterval(() => {
+            this.now = this.socket.clock.now();
+        }, 100);
+        this.socket.connect()
+            .catch((err) => {
+                this.error = "Error connecting to the game server: " + err;
+            });
+    },
+})
@@ -2,6 +2,9 @@
 // Use of this source code is governed by the MIT license
 // that can be found in the LICENSE file.
 
+// version of the websocket protocol this client speaks
+const protocolVersion = 1;
+
 var app = new Vue({
     el: "#game",
     directives: {
@@ -14,10 +17,16 @@ var app = new Vue({
     data: {
         socket: null,
         game: null,
+        updateRequested: false,
+        now: Date.now(),
         settings: false,
         playerName: "",
         playerNameErr: "",
         code: "",
+        private: false,
+        passphrase: "",
+        newPassphrase: "",
+        newSpectatorPassphrase: "",
         loading: false,
         error: null,
         addName: "",
@@ -25,6 +34,24 @@ var app = new Vue({
         stealCheck: false,
         notification: "",
         startTurnReady: false,
+        draftSetup: false,
+        typed: "",
+        chat: [],
+        chatOpen: false,
+        playersOpen: false,
+        chatChannel: "all",
+        chatText: "",
+        unreadChat: 0,
+        floatingReactions: [],
+        reactionCount: 0,
+        reactionEmoji: {
+            laugh: "\u{1F602}",
+            facepalm: "\u{1F926}",
+            applause: "\u{1F44F}",
+        },
+        typedFeed: [],
+        captain1: "",
+        captain2: "",
         nameHints: [
             "Someone you're playing with",
             "A family member",
@@ -76,15 +103,18 @@ var app = new Vue({
             }
             return 0;
         },
+        timeLeft: function () {
+            return timerLeft(this.game && this.game.timer, this.now);
+        },
         timerPercent: function () {
             if (this.game && this.game.timer) {
-                return (this.game.timer.left / this.game.timer.seconds) * 100;
+                return (this.timeLeft / this.game.timer.seconds) * 100;
             }
             return 0;
         },
         timerStyle: function () {
             if (this.game && this.game.timer) {
-                let ratio = this.game.timer.left / this.game.timer.seconds;
+                let ratio = this.timeLeft / this.game.timer.seconds;
                 if (ratio < .25) {
                     return "danger";
                 }
@@ -98,13 +128,13 @@ var app = new Vue({
             if (this.game && this.game.timer && this.game.stage === "setup") {
                 // wait 5 seconds, then display name hints evenly across the remaining timer time
                 const firstHintDelay = 5;
-                let passed = Math.round(this.game.timer.seconds - this.game.timer.left);
+                let passed = Math.round(this.game.timer.seconds - this.timeLeft);
                 if (passed < firstHintDelay) {
                     return "";
                 }
 
                 let partition = (this.game.timer.seconds - firstHintDelay) / this.nameHints.length;
-                let index = Math.round(this.game.timer.left / partition);
+                let index = Math.round(this.timeLeft / partition);
 
                 return this.nameHints[index];
             }
@@ -138,10 +168,44 @@ var app = new Vue({
         isWaiting: function () {
             return this.team === this.waitingTeam;
         },
+        hearsTimer: function () {
+            // everyone hears the setup timer, while only the team on the clock hears turn and steal timers
+            if (!this.game) { return false; }
+            switch (this.game.stage) {
+                case "setup":
+                    return true;
+                case "playing":
+                    return this.isGuessing;
+                case "stealing":
+                    return this.isWaiting;
+            }
+            return false;
+        },
         isClueGiver: function () {
             if (!this.game || !this.game.clueGiver) { return null; }
             return this.game.clueGiver.name === this.playerName;
         },
+        players: function () {
+            if (!this.game) { return []; }
+            return this.game.team1.players.concat(this.game.team2.players);
+        },
+        playerNames: function () {
+            return this.players.map(player => player.name);
+        },
+        switchRequested: function () {
+            if (!this.game || !this.game.switchRequests) { return false; }
+            return this.game.switchRequests.includes(this.playerName);
+        },
+        draftCaptain: function () {
+            if (!this.game || !this.game.draft.active) { return ""; }
+            if (this.game.draft.picking === 1) {
+                return this.game.draft.captain1;
+            }
+            return this.game.draft.captain2;
+        },
+        chatMessages: function () {
+            return this.chat.filter(msg => msg.channel === this.chatChannel);
+        },
         gameStarted: function () {
             if (!this.game) { return false; }
             return this.game.stage !== "pregame";
@@ -152,11 +216,51 @@ var app = new Vue({
             switch (msg.type) {
                 case "state":
                     this.loading = false;
+                    this.updateRequested = false;
                     this.stateChange(msg.data, this.game);
                     this.game = msg.data;
                     break;
+                case "patch":
+                    let state = applyPatch(this.game, msg.data);
+                    if (!state) {
+                        this.requestUpdate();
+                        break;
+                    }
+                    this.loading = false;
+                    this.stateChange(state, this.game);
+                    this.game = state;
+                    break;
                 case "error":
-                    this.error = msg.data;
+                    this.loading = false;
+                    if (this.game) {
+                        // keep playing, errors once in a game are usually a rule being broken
+                        this.notification = msg.data.message;
+                    } else {
+                        this.error = msg.data.message;
+                    }
+                    break;
+                case "chathistory":
+                    this.chat = msg.data || [];
+                    break;
+                case "chat":
+                    this.chat.push(msg.data);
+                    if (!this.chatOpen) {
+                        this.unreadChat++;
+                    }
+                    break;
+                case "reaction":
+                    let reaction = { id: this.reactionCount++, reaction: msg.data.reaction };
+                    this.floatingReactions.push(reaction);
+                    setTimeout(() => {
+                        this.floatingReactions.splice(this.floatingReactions.indexOf(reaction), 1);
+                    }, 2000);
+                    break;
+                case "clue":
+                case "guess":
+                    this.typedFeed.push({ type: msg.type, player: msg.data.player, text: msg.data.text, correct: msg.data.correct });
+                    break;
+                case "token":
+                    localStorage.setItem(this.tokenKey(), msg.data);
                     break;
                 case "name":
                     this.currentName = msg.data;
@@ -164,12 +268,8 @@ var app = new Vue({
                 case "stealcheck":
                     this.stealCheck = true;
                     break;
-                case "ping":
-                    this.socket.send({ type: "pong" });
-                    break;
                 case "playsound":
-                    let sound = new Audio("/audio/" + msg.data + ".mp3");
-                    sound.play();
+                    this.playSound(msg.data);
                     break;
                 case "startcheck":
                     this.startTurnReady = true;
@@ -188,12 +288,54 @@ var app = new Vue({
             this.socket.send({
                 type: "join",
                 data: {
+                    version: protocolVersion,
                     code: this.code,
                     name: this.playerName,
+                    token: localStorage.getItem(this.tokenKey()) || "",
+                    passphrase: this.passphrase,
+                    // picks up where the last connection left off, so nothing sent in between is missed
+                    lastSeq: this.socket.lastSeq,
                 }
             });
             localStorage.setItem("playerName", this.playerName);
         },
+        playSound: function (name) {
+            let sound = new Audio("/audio/" + name + ".mp3");
+            sound.play();
+        },
+        tick: function () {
+            let previous = this.timeLeft;
+            this.now = this.socket.clock.now();
+            let left = this.timeLeft;
+
+            // make sure the half second ticking sound doesn't overlap with the timer alarm sound
+            if (!this.hearsTimer || left < .5 || left >= previous) {
+                return;
+            }
+            let ratio = left / this.game.timer.seconds;
+            if (ratio <= .25 && Math.ceil(left * 2) !== Math.ceil(previous * 2)) {
+                // tick every half second
+                this.playSound("tick");
+            } else if (ratio <= .5 && Math.ceil(left) !== Math.ceil(previous)) {
+                // tick every second
+                this.playSound("tick");
+            }
+        },
+        requestUpdate: function () {
+            // missed an update, only ask for the full state once until it arrives
+            if (this.updateRequested) {
+                return;
+            }
+            this.updateRequested = true;
+            this.send("requestupdate");
+        },
+        tokenKey: function () {
+            // rejoin tokens are per player per game
+            return "token-" + this.code + "-" + this.playerName;
+        },
+        releasePlayer: function (name) {
+            this.send("releaseplayer", name);
+        },
         startGame: function () {
             this.loading = true;
             this.send("start");
@@ -211,6 +353,24 @@ var app = new Vue({
         send: function (type, data) {
             this.socket.send({ type: type, data: data });
         },
+        toggleChat: function () {
+            this.chatOpen = !this.chatOpen;
+            this.unreadChat = 0;
+        },
+        sendChat: function () {
+            if (!this.chatText) {
+                return;
+            }
+            this.send("chat", { channel: this.chatChannel, text: this.chatText });
+            this.chatText = "";
+        },
+        sendTyped: function () {
+            if (!this.typed) {
+                return;
+            }
+            this.send(this.isClueGiver ? "clue" : "guess", this.typed);
+            this.typed = "";
+        },
         startTurn: function () {
             this.send("startturn");
             this.startTurnReady = false;
@@ -224,6 +384,40 @@ var app = new Vue({
             }
             this.socket.send({ type: "namesperplayer", data: this.game.namesPerPlayer });
         },
+        startDraft: function () {
+            this.send("startdraft", [this.captain1, this.captain2]);
+            this.draftSetup = false;
+        },
+        draftedPlayers: function (team) {
+            if (!this.game.draft.active) {
+                return team.players;
+            }
+            return team.players.filter(player => !this.game.draft.unassigned.includes(player.name));
+        },
+        moveUp: function (team, index) {
+            let players = team.players.slice();
+            players.splice(index - 1, 0, players.splice(index, 1)[0]);
+            let other = team === this.game.team1 ? this.game.team2 : this.game.team1;
+            this.send("rotationorder", players.concat(other.players).map(player => player.name));
+        },
+        setScoring: function (field, value, index) {
+            let scoring = JSON.parse(JSON.stringify(this.game.scoring));
+            if (field === "round") {
+                scoring.roundPoints[index] = value;
+            } else {
+                scoring[field] = value;
+            }
+            this.send("scoring", scoring);
+        },
+        setSeriesGames: function (increment) {
+            let games = this.game.series.games + increment;
+            if (games < 1) {
+                games = 1;
+            } else if (games > 9) {
+                games = 9;
+            }
+            this.send("seriesgames", games);
+        },
         stealCheckConfirm: function (correct) {
             this.stealConfirm = false;
             this.currentName = "";
@@ -242,6 +436,7 @@ var app = new Vue({
             if (oldState.stage !== newState.stage) {
                 if (newState.stage !== "stealing") {
                     this.currentName = "";
+                    this.typedFeed = [];
                 }
                 if (newState.stage === "setup") {
                     shuffle(this.nameHints);
@@ -257,11 +452,11 @@ var app = new Vue({
     mounted: function () {
         this.playerName = localStorage.getItem("playerName");
         this.code = document.getElementById("game").getAttribute("data-code");
+        this.private = document.getElementById("game").getAttribute("data-private") === "true";
         this.socket = GameSocket(this.receive, this.reconnect);
+        // timers count down locally to the deadline sent by the server
+        setInterval(this.tick, 100);
         this.socket.connect()
-            .then(() => {
-                this.socket.send({ type: "requestupdate" });
-            })
             .catch((err) => {
                 this.error = "Error connecting to the game server: " + err;
             });
@@ -281,15 +476,32 @@ var app = new Vue({
 
 function GameSocket(onmessage, onreconnect) {
     const retryPoll = 1500;
+    const ackDelay = 1000;
     let url = window.location.origin.toString().replace("http://", "ws://").replace("https://", "wss://") + "/game";
-    return {
+    let socket = {
+        lastSeq: 0,
         connect() {
             return new Promise((resolve, reject) => {
                 this.connection = new WebSocket(url);
                 this.connection.onopen = () => {
                     this.manualClose = false;
+                    this.joined = false;
                     this.connection.onmessage = (event) => {
-                        onmessage(JSON.parse(event.data));
+                        let msg = JSON.parse(event.data);
+                        if (msg.seq) {
+                            this.lastSeq = msg.seq;
+                            this.ack();
+                        }
+                        switch (msg.type) {
+                            case "welcome":
+                                this.joined = true;
+                                this.clock.welcome(msg.data.serverTime);
+                                break;
+                            case "timesync":
+                                this.clock.synced(msg.data);
+                                return;
+                        }
+                        onmessage(msg);
                     };
                     this.connection.onerror = (event) => {
                         console.log("Web Socket error, retrying: ", event);
@@ -320,6 +532,19 @@ function GameSocket(onmessage, onreconnect) {
             }
             this.connection.send(JSON.stringify(data));
         },
+        ack() {
+            // acks let the server let go of messages, they don't need to be sent for every message
+            if (this.ackTimer) {
+                return;
+            }
+            this.ackTimer = setTimeout(() => {
+                this.ackTimer = null;
+                // nothing can be sent on a new connection until it has joined
+                if (this.joined && this.connection.readyState === WebSocket.OPEN) {
+                    this.connection.send(JSON.stringify({ type: "ack", data: this.lastSeq }));
+                }
+            }, ackDelay);
+        },
         close(code, reason) {
             if (this.connection) {
                 this.manualClose = true;
@@ -339,6 +564,13 @@ function GameSocket(onmessage, onreconnect) {
             }, this.retryPoll);
         },
     };
+    // clock syncs are only useful right away, so they aren't retried like other messages
+    socket.clock = ServerClock(data => {
+        if (socket.joined && socket.connection.readyState === WebSocket.OPEN) {
+            socket.connection.send(JSON.stringify(data));
+        }
+    });
+    return socket;
 }
 
 function shuffle(a) {
@@ -0,0 +1,123 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+// streaming overlay of a game, meant to be used as a browser source with a transparent background
+var app = new Vue({
+    el: "#overlay",
+    data: {
+        socket: null,
+        game: null,
+        code: "",
+        layout: "horizontal",
+        show: ["scores", "timer", "round", "events"],
+        events: [],
+        eventCount: 0,
+        now: Date.now(),
+    },
+    computed: {
+        roundRule: function () {
+            if (!this.game) { return ""; }
+            switch (this.game.round) {
+                case 1:
+                    return "Say Anything";
+                case 2:
+                    return "Silent Clues Only";
+                case 3:
+                    return "One Word Only";
+            }
+            return "";
+        },
+        team1Events: function () {
+            return this.events.filter(event => event.team === 1);
+        },
+        team2Events: function () {
+            return this.events.filter(event => event.team === 2);
+        },
+        timeLeft: function () {
+            return timerLeft(this.game && this.game.timer, this.now);
+        },
+        timerPercent: function () {
+            if (this.game && this.game.timer && this.game.timer.seconds) {
+                return (this.timeLeft / this.game.timer.seconds) * 100;
+            }
+            return 0;
+        },
+        timerStyle: function () {
+            if (this.game && this.game.timer && this.game.timer.seconds) {
+                let ratio = this.timeLeft / this.game.timer.seconds;
+                if (ratio < .25) {
+                    return "danger";
+                }
+                if (ratio < .5) {
+                    return "warning";
+                }
+            }
+            return "success";
+        },
+    },
+    methods: {
+        receive: function (msg) {
+            let state = null;
+            switch (msg.type) {
+                case "state":
+                    state = msg.data;
+                    break;
+                case "patch":
+                    state = applyPatch(this.game, msg.data);
+                    if (!state) {
+                        this.socket.send({ type: "requestupdate" });
+                        return;
+                    }
+                    break;
+                case "error":
+                    // overlays are on stream, so errors are only logged
+                    console.log("Overlay error: ", msg.data.message);
+                    return;
+                default:
+                    return;
+            }
+
+            if (this.game) {
+                this.scoreEvent(1, state.stats.team1Score - this.game.stats.team1Score);
+                this.scoreEvent(2, state.stats.team2Score - this.game.stats.team2Score);
+            }
+            this.game = state;
+        },
+        scoreEvent: function (team, points) {
+            if (points <= 0) {
+                return;
+            }
+            let event = { id: this.eventCount++, team: team, points: points };
+            this.events.push(event);
+            setTimeout(() => {
+                this.events.splice(this.events.indexOf(event), 1);
+            }, 2000);
+        },
+        shown: function (element) {
+            return this.show.includes(element);
+        },
+    },
+    mounted: function () {
+        this.code = document.getElementById("overlay").getAttribute("data-code");
+
+        let params = new URLSearchParams(window.location.search);
+        if (params.get("layout") === "vertical") {
+            this.layout = "vertical";
+        }
+        if (params.get("show")) {
+            this.show = params.get("show").split(",").map(element => element.trim().toLowerCase());
+        }
+
+        this.socket = ViewerSocket(this.code, "overlay", this.receive);
+        // timers count down locally to the deadline sent by the server
+        setInterval(() => {
+            this.now = this.socket.clock.now();
+        }, 100);
+        this.socket.connect()
+            .catch((err) => {
+                console.log("Error connecting to the game server: ", err);
+                this.socket.retry();
+            });
+    },
+})
@@ -0,0 +1,64 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+// applyPatch applies a patch of changed state fields from the server, returning the new state.  Returns null if a
+// version was missed, and a full state update needs to be requested
+function applyPatch(state, patch) {
+    if (!state || patch.version > state.version + 1) {
+        return null;
+    }
+    if (patch.version <= state.version) {
+        // already have a newer state
+        return state;
+    }
+
+    let next = Object.assign({}, state, patch.fields);
+    if (patch.removed) {
+        patch.removed.forEach(field => delete next[field]);
+    }
+    next.version = patch.version;
+    return next;
+}
+
+// ServerClock estimates the offset between this browser's clock and the server's, so timers can count down to the
+// deadlines sent by the server without the server sending every tick
+function ServerClock(send) {
+    const syncPoll = 30000;
+    return {
+        offset: 0,
+        roundTrip: Infinity,
+        // welcome starts over with a rough offset from the welcome message, which timesync replies then refine
+        welcome(serverTime) {
+            this.offset = serverTime - Date.now();
+            this.roundTrip = Infinity;
+            this.sync();
+            if (!this.poll) {
+                this.poll = setInterval(() => this.sync(), syncPoll);
+            }
+        },
+        sync() {
+            send({ type: "timesync", data: Date.now() });
+        },
+        synced(reply) {
+            let now = Date.now();
+            let roundTrip = now - reply.client;
+            // the reply with the shortest round trip is the most accurate
+            if (roundTrip <= this.roundTrip) {
+                this.roundTrip = roundTrip;
+                this.offset = reply.server + (roundTrip / 2) - now;
+            }
+        },
+        now() {
+            return Date.now() + this.offset;
+        },
+    };
+}
+
+// timerLeft is how many seconds a game timer has left at the passed in server time
+function timerLeft(timer, now) {
+    if (!timer || !timer.deadline) {
+        return 0;
+    }
+    return Math.max(0, (timer.deadline - now) / 1000);
+}
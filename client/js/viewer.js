@@ -0,0 +1,73 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+// version of the websocket protocol this client speaks
+const protocolVersion = 1;
+
+// ViewerSocket watches a game with the given viewer role, rewatching whenever the connection is retried.
+// Private games can be watched by passing the passphrase in the url, i.e. ?passphrase=secret
+function ViewerSocket(code, role, onmessage) {
+    const retryPoll = 1500;
+    let url = window.location.origin.toString().replace("http://", "ws://").replace("https://", "wss://") + "/game";
+    let socket = {
+        passphrase: new URLSearchParams(window.location.search).get("passphrase") || "",
+        connect() {
+            // errors from the server close the connection, and retrying them would just error again
+            this.errored = false;
+            return new Promise((resolve, reject) => {
+                this.connection = new WebSocket(url);
+                this.connection.onopen = () => {
+                    this.connection.onmessage = (event) => {
+                        let msg = JSON.parse(event.data);
+                        switch (msg.type) {
+                            case "error":
+                                this.errored = true;
+                                break;
+                            case "welcome":
+                                this.clock.welcome(msg.data.serverTime);
+                                break;
+                            case "timesync":
+                                this.clock.synced(msg.data);
+                                return;
+                        }
+                        onmessage(msg);
+                    };
+                    this.connection.onclose = () => {
+                        if (!this.errored) {
+                            this.retry();
+                        }
+                    };
+                    this.watch();
+                    resolve();
+                };
+
+                this.connection.onerror = (event) => {
+                    reject(event);
+                };
+            });
+        },
+        send(data) {
+            if (this.connection && this.connection.readyState === WebSocket.OPEN) {
+                this.connection.send(JSON.stringify(data));
+            }
+        },
+        watch() {
+            this.connection.send(JSON.stringify({
+                type: "watch",
+                data: { version: protocolVersion, code: code, role: role, passphrase: this.passphrase },
+            }));
+        },
+        retry() {
+            setTimeout(() => {
+                this.connect()
+                    .catch((err) => {
+                        console.log("Web Socket Errored, retrying: ", err);
+                        this.retry();
+                    });
+            }, retryPoll);
+        },
+    };
+    socket.clock = ServerClock(data => socket.send(data));
+    return socket;
+}
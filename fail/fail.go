@@ -13,7 +13,7 @@ import (
 // of incorrect client input
 type Failure struct {
 	Message    string `json:"message,omitempty"`
-	HTTPStatus int    `json:"-"` //gets set in the error response
+	HTTPStatus int    `json:"status,omitempty"` //gets set in the error response, and sent to websocket clients
 }
 
 func (f *Failure) Error() string {
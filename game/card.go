@@ -0,0 +1,86 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"fmt"
+)
+
+// Card is a shareable summary of a finished game
+type Card struct {
+	Code       string
+	Winner     int
+	Team1Score int
+	Team2Score int
+	Awards     []Award
+}
+
+// Award is one of the headline stats of a finished game
+type Award struct {
+	Title  string
+	Detail string
+}
+
+var reactionTitles = map[string]string{
+	reactionLaugh:    "Laughs",
+	reactionFacepalm: "Facepalms",
+	reactionApplause: "Applause",
+}
+
+// Card returns the summary card of the game, which is only available once the game has ended
+func (g *Game) Card() (*Card, bool) {
+	var card *Card
+	g.call(func() {
+		card = newCard(g)
+	})
+	return card, card != nil
+}
+
+// newCard builds the summary card of an ended game, or nil if the game hasn't ended
+func newCard(g *Game) *Card {
+	if g.Stage != stageEnd {
+		return nil
+	}
+
+	c := &Card{
+		Code:       g.Code,
+		Winner:     g.Stats.Winner,
+		Team1Score: g.Stats.Team1Score,
+		Team2Score: g.Stats.Team2Score,
+	}
+
+	if g.Stats.BestClueGiver.Player != "" {
+		c.Awards = append(c.Awards, Award{
+			Title:  "Best Clue Giver",
+			Detail: fmt.Sprintf("%s with %d guesses", g.Stats.BestClueGiver.Player, g.Stats.BestClueGiver.Guesses),
+		})
+	}
+	if g.Stats.MostStolen.Player != "" {
+		c.Awards = append(c.Awards, Award{
+			Title:  "Most Stolen From",
+			Detail: fmt.Sprintf("%s with %d steals", g.Stats.MostStolen.Player, g.Stats.MostStolen.Steals),
+		})
+	}
+	if g.Stats.EasiestName.Name != "" {
+		c.Awards = append(c.Awards, Award{
+			Title:  "Easiest Name",
+			Detail: fmt.Sprintf("%s in %s", g.Stats.EasiestName.Name, g.Stats.EasiestName.GuessTime),
+		})
+	}
+	if g.Stats.HardestName.Name != "" {
+		c.Awards = append(c.Awards, Award{
+			Title:  "Hardest Name",
+			Detail: fmt.Sprintf("%s in %s", g.Stats.HardestName.Name, g.Stats.HardestName.GuessTime),
+		})
+	}
+	for _, r := range g.Stats.Reactions {
+		c.Awards = append(c.Awards, Award{
+			Title:  "Most " + reactionTitles[r.Reaction],
+			Detail: fmt.Sprintf("%s with %d", r.Player, r.Count),
+		})
+	}
+
+	return c
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+const (
+	chatTeam = "team" // only the player's team sees the message
+	chatAll  = "all"  // everyone in the game sees the message
+)
+
+const (
+	maxChatLength  = 280
+	chatScrollback = 200 // how many chat messages are kept per game
+)
+
+var chatRate = &RateLimit{
+	Type:   "chat",
+	Limit:  10,
+	Period: 15 * time.Second,
+}
+
+type chatMsg struct {
+	Channel string    `json:"channel"`
+	Team    int       `json:"team"`
+	Player  string    `json:"player"`
+	Text    string    `json:"text"`
+	Time    time.Time `json:"time"`
+}
+
+var profanity = func() *regexp.Regexp {
+	words := make([]string, len(dirtyWords))
+	for i := range dirtyWords {
+		words[i] = regexp.QuoteMeta(dirtyWords[i])
+	}
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `)(s|es|ed|ing|er|y)?\b`)
+}()
+
+func (g *Game) chat(p *Player, channel, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	if len(text) > maxChatLength {
+		return fail.New("Chat messages can't be longer than %d characters", maxChatLength)
+	}
+
+	if channel != chatTeam && channel != chatAll {
+		return fail.New("%s is an invalid chat channel", channel)
+	}
+
+	if _, err := chatRate.Attempt(g.Code + "/" + p.Name); err != nil {
+		return fail.New("You are sending messages too quickly, slow down")
+	}
+
+	if g.ChatMuted && channel == chatAll && (g.Stage == stagePlaying || g.Stage == stageStealing) {
+		return fail.New("The leader has muted chat during turns")
+	}
+
+	if g.TypedGuesses && g.Stage == stagePlaying && g.ClueGiver != nil && g.ClueGiver.Name == p.Name {
+		// clues have to go through the clue rules when guesses are typed, so the clue giver can't chat them
+		return fail.New("Clue givers can't chat during their turn, send clues instead")
+	}
+
+	msg := chatMsg{
+		Channel: channel,
+		Team:    2,
+		Player:  p.Name,
+		Text:    censor(text),
+		Time:    g.clock.Now(),
+	}
+	if onTeam1(g, p) {
+		msg.Team = 1
+	}
+
+	g.chatHistory = append(g.chatHistory, msg)
+	if len(g.chatHistory) > chatScrollback {
+		g.chatHistory = g.chatHistory[len(g.chatHistory)-chatScrollback:]
+	}
+
+	send := Msg{Type: "chat", Data: msg}
+	if channel == chatAll || msg.Team == 1 {
+		g.Team1.sendMsg(send)
+	}
+	if channel == chatAll || msg.Team == 2 {
+		g.Team2.sendMsg(send)
+	}
+	return nil
+}
+
+func (g *Game) muteChat(who *Player, muted bool) error {
+	if !who.isLeader() {
+		return fail.New("Only game leaders can mute chat")
+	}
+
+	g.ChatMuted = muted
+	return nil
+}
+
+// sendChatHistory sends the chat scrollback the player is allowed to see
+func sendChatHistory(g *Game, p *Player) {
+	team := 2
+	if onTeam1(g, p) {
+		team = 1
+	}
+
+	history := make([]chatMsg, 0, len(g.chatHistory))
+	for _, msg := range g.chatHistory {
+		if msg.Channel == chatAll || msg.Team == team {
+			history = append(history, msg)
+		}
+	}
+
+	p.SendMsg(Msg{Type: "chathistory", Data: history})
+}
+
+// censor blanks out any profanity in the text
+func censor(text string) string {
+	return profanity.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", len(word))
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock only moves forward when it's advanced, so a test can play through a game's timers without waiting on
+// them
+type fakeClock struct {
+	sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a pending After or Ticker on a fake clock, tickers have a period and are rescheduled each time they
+// fire
+type fakeTimer struct {
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, time.May, 1, 19, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return c.add(d, 0).c
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	return &fakeTicker{clock: c, timer: c.add(d, d)}
+}
+
+func (c *fakeClock) add(d, period time.Duration) *fakeTimer {
+	c.Lock()
+	defer c.Unlock()
+
+	t := &fakeTimer{
+		at:     c.now.Add(d),
+		period: period,
+		c:      make(chan time.Time, 1), // buffered like time.Timer, so a fire nobody is waiting on is never blocked
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward, firing every timer that comes due along the way in order
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		next := -1
+		for i, t := range c.timers {
+			if !t.at.After(end) && (next == -1 || t.at.Before(c.timers[next].at)) {
+				next = i
+			}
+		}
+		if next == -1 {
+			break
+		}
+
+		t := c.timers[next]
+		c.now = t.at
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		if t.period > 0 {
+			t.at = t.at.Add(t.period)
+			continue
+		}
+		c.remove(next)
+	}
+	c.now = end
+}
+
+func (c *fakeClock) remove(i int) {
+	c.timers = append(c.timers[:i], c.timers[i+1:]...)
+}
+
+type fakeTicker struct {
+	clock *fakeClock
+	timer *fakeTimer
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.timer.c }
+
+func (t *fakeTicker) Stop() {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+	for i := range t.clock.timers {
+		if t.clock.timers[i] == t.timer {
+			t.clock.remove(i)
+			return
+		}
+	}
+}
@@ -28,6 +28,11 @@ func generateCode(length int) string {
 		return generateCode(length)
 	}
 
+	// or still holding the results of a previous game
+	if resultsArchived(code) {
+		return generateCode(length)
+	}
+
 	return code
 }
 
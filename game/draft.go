@@ -0,0 +1,183 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"log"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+const draftSecondsPerPick = 20 // how much time a captain gets to make each pick
+
+// draft tracks two captains taking turns picking their teams from the unassigned players
+// unassigned players still live in one of the teams so they receive updates, but they are only placed
+// on their final team once picked
+type draft struct {
+	Active     bool     `json:"active"`
+	Captain1   string   `json:"captain1"`
+	Captain2   string   `json:"captain2"`
+	Picking    int      `json:"picking"` // which captain's team is currently picking
+	Unassigned []string `json:"unassigned"`
+	pick       int      // incremented every pick, so stale pick timers can be ignored
+}
+
+func (g *Game) startDraft(who *Player, captain1, captain2 string) error {
+	if g.Stage != stagePregame {
+		return fail.New("A draft can only be held before the game starts")
+	}
+
+	if !who.isLeader() {
+		return fail.New("Only game leaders can start a draft")
+	}
+
+	if g.Draft.Active {
+		return fail.New("A draft is already in progress")
+	}
+
+	if captain1 == captain2 {
+		return fail.New("Each team needs a different captain")
+	}
+
+	c1, ok := findPlayer(g, captain1)
+	if !ok {
+		return fail.New("%s is not in this game", captain1)
+	}
+	c2, ok := findPlayer(g, captain2)
+	if !ok {
+		return fail.New("%s is not in this game", captain2)
+	}
+
+	movePlayer(g, c1, &g.Team1)
+	movePlayer(g, c2, &g.Team2)
+
+	g.Draft = draft{
+		Active:   true,
+		Captain1: c1.Name,
+		Captain2: c2.Name,
+		Picking:  1,
+		pick:     g.Draft.pick,
+	}
+
+	for _, p := range append(g.Team1.copyPlayers(), g.Team2.copyPlayers()...) {
+		if p != c1 && p != c2 {
+			g.Draft.Unassigned = append(g.Draft.Unassigned, p.Name)
+		}
+	}
+
+	log.Printf("Draft started in game %s", g.Code)
+	nextPick(g)
+	return nil
+}
+
+func (g *Game) draftPick(who *Player, name string) error {
+	if !g.Draft.Active {
+		return fail.New("There is no draft in progress")
+	}
+
+	captain, team := g.Draft.Captain1, &g.Team1
+	if g.Draft.Picking == 2 {
+		captain, team = g.Draft.Captain2, &g.Team2
+	}
+
+	if who.Name != captain {
+		return fail.New("It is %s's pick", captain)
+	}
+
+	index := -1
+	for i := range g.Draft.Unassigned {
+		if g.Draft.Unassigned[i] == name {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return fail.New("%s is not available to be picked", name)
+	}
+
+	if p, ok := findPlayer(g, name); ok {
+		movePlayer(g, p, team)
+	}
+	g.Draft.Unassigned = append(g.Draft.Unassigned[:index], g.Draft.Unassigned[index+1:]...)
+
+	if g.Draft.Picking == 1 {
+		g.Draft.Picking = 2
+	} else {
+		g.Draft.Picking = 1
+	}
+
+	nextPick(g)
+	return nil
+}
+
+// nextPick starts the timer for the next pick, or ends the draft if there is no one left to pick
+func nextPick(g *Game) {
+	stopTimer(g)
+	g.Draft.pick++
+
+	if len(g.Draft.Unassigned) == 0 {
+		endDraft(g)
+		return
+	}
+
+	pick := g.Draft.pick
+	g.startTimer(draftSecondsPerPick, nil, nil, func() {
+		if !g.Draft.Active || g.Draft.pick != pick {
+			return
+		}
+		autoAssign(g)
+	})
+}
+
+// autoAssign places all unpicked players onto the smaller team once a captain runs out of time
+func autoAssign(g *Game) {
+	for _, name := range g.Draft.Unassigned {
+		p, ok := findPlayer(g, name)
+		if !ok {
+			continue
+		}
+		// take them off their current team first so they aren't counted in the team size
+		g.Team1.removePlayer(p.Name)
+		g.Team2.removePlayer(p.Name)
+		if len(g.Team1.Players) <= len(g.Team2.Players) {
+			g.Team1.addExistingPlayer(p)
+		} else {
+			g.Team2.addExistingPlayer(p)
+		}
+	}
+	g.Draft.Unassigned = nil
+	g.Team1.sendNotification("Time ran out, the remaining players have been assigned to teams")
+	g.Team2.sendNotification("Time ran out, the remaining players have been assigned to teams")
+	endDraft(g)
+}
+
+func endDraft(g *Game) {
+	g.Draft.Active = false
+	g.Draft.Picking = 0
+	log.Printf("Draft finished in game %s", g.Code)
+}
+
+// draftJoin adds a player who joined during a draft to the pool of players to be picked
+func draftJoin(g *Game, name string) {
+	if g.Draft.Active {
+		g.Draft.Unassigned = append(g.Draft.Unassigned, name)
+	}
+}
+
+func findPlayer(g *Game, name string) (*Player, bool) {
+	if p, ok := g.Team1.player(name); ok {
+		return p, true
+	}
+	return g.Team2.player(name)
+}
+
+func movePlayer(g *Game, p *Player, team *Team) {
+	if _, ok := team.player(p.Name); ok {
+		return
+	}
+	g.Team1.removePlayer(p.Name)
+	g.Team2.removePlayer(p.Name)
+	team.addExistingPlayer(p)
+}
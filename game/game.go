@@ -5,11 +5,9 @@
 package game
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
-	"sync"
 	"time"
 
 	"github.com/timshannon/threenamesinahat/fail"
@@ -19,6 +17,7 @@ import (
 type Msg struct {
 	Type string      `json:"type"`
 	Data interface{} `json:"data"`
+	Seq  uint64      `json:"seq,omitempty"` // order of messages sent to a client, see outbox.go
 }
 
 // pregame -> setup -> round1 -> round2 -> round3 -> end
@@ -35,10 +34,10 @@ const (
 	secondsPerTurn      = 30 // how much time each player gets per turn
 	setupSecondsPerName = 30 // how much time per name each player gets during game setup
 	secondsToSteal      = 15 // how much time the opposing team gets to steal
+	minTeamPlayers      = 2  // each team needs someone to give clues and someone to guess
 )
 
 const (
-	soundTick       = "tick"
 	soundTimerAlarm = "timer-alarm"
 	soundScore      = "score"
 	soundNotify     = "notify"
@@ -47,11 +46,19 @@ const (
 	soundGameLose   = "game-lose"
 )
 
-// Game tracks the state of a single Game
+// Game tracks the state of a single Game.  All of a game's state is owned by its event loop, so methods and
+// functions on a game must only be run from inside an event, see loop.go
 type Game struct {
-	sync.RWMutex // manage the lock in methods, functions expect lock to be already managed
 	gameState
-	rand *rand.Rand
+	clock   Clock
+	rand    *rand.Rand // seeded when the game is created, so a game can be replayed with the same seed
+	viewers []*Viewer
+
+	events chan func()
+	done   chan struct{}
+
+	playerStates stateTracker // last state sent to players
+	viewerStates stateTracker // last state sent to viewers
 }
 
 type nameItem struct {
@@ -59,9 +66,7 @@ type nameItem struct {
 	player string
 }
 
-// gameState is copied out for JSON encoding
-// mutexes can't be copied, so sync access is managed in Game
-// RWMutex is Read Locked, then the state is copied to send to players
+// gameState is the part of a game that is encoded and sent to players
 type gameState struct {
 	Code           string  `json:"code"`
 	NamesPerPlayer int     `json:"namesPerPlayer"`
@@ -71,26 +76,35 @@ type gameState struct {
 	Stage          string  `json:"stage"`
 	Round          int     `json:"round"`
 	Timer          struct {
-		Seconds      int `json:"seconds"`
-		Left         int `json:"left"`
-		durationLeft time.Duration
-		stop         chan bool
+		Seconds  int   `json:"seconds"`
+		Deadline int64 `json:"deadline"` // unix milliseconds when the timer runs out, clients count down locally
+		deadline time.Time
+		stop     chan bool
+		cancel   func() // stops the timer without running its finish
 	} `json:"timer"`
 	ClueGiver *Player `json:"clueGiver"`
 
-	nameList []nameItem
+	nameList    []nameItem
+	history     []NameResult // names guessed so far this game
+	lastResults *Results     // results of the previous game played
 
+	Rotation       string `json:"rotation"` // how the next clue giver on a team is picked
 	clueGiverTrack struct {
 		team1Index int
 		team2Index int
+		team1Bag   []string // players on team 1 who haven't had a turn yet when picking randomly
+		team2Bag   []string
 		team1      bool
+		turn       int // total turns taken
 	}
 	canSteal bool
 	Stats    struct {
-		Winner        int `json:"winner"`
-		Team1Score    int `json:"team1Score"`
-		Team2Score    int `json:"team2Score"`
-		BestClueGiver struct {
+		Winner         int            `json:"winner"`
+		Team1Score     int            `json:"team1Score"`
+		Team2Score     int            `json:"team2Score"`
+		Team1Breakdown scoreBreakdown `json:"team1Breakdown"`
+		Team2Breakdown scoreBreakdown `json:"team2Breakdown"`
+		BestClueGiver  struct {
 			Player  string `json:"player"`
 			Guesses int    `json:"guesses"`
 			stats   map[string]int
@@ -114,47 +128,62 @@ type gameState struct {
 			Round     int    `json:"round"`
 			guessTime time.Duration
 		} `json:"hardestName"` // which name took the longest to guess
-		nameTime time.Time
+		Reactions []reactionAward `json:"reactions"` // who earned the most of each reaction
+		reactions map[string]map[string]int
+		nameTime  time.Time
 	} `json:"stats"`
-}
+	Series  series `json:"series"`
+	Rematch bool   `json:"rematch"` // a previous game has been played with this code
+	Draft   draft  `json:"draft"`
+
+	TimeBank timeBank `json:"timeBank"`
+	Scoring  scoring  `json:"scoring"`
+
+	TypedGuesses bool `json:"typedGuesses"` // guesses and clues are typed instead of spoken
 
-// MarshalJSON implements the json marchaller interface so that locks can be mananged when marshalling
-func (g *Game) MarshalJSON() ([]byte, error) {
-	g.RLock()
-	defer g.RUnlock()
-	return json.Marshal(g.gameState)
+	TurnSummaries []turnSummary `json:"turnSummaries"`
+
+	ChatMuted   bool `json:"chatMuted"` // chat to all players is muted during turns
+	chatHistory []chatMsg
+
+	SwitchApproval bool     `json:"switchApproval"` // switching teams requires the leader's approval
+	SwitchRequests []string `json:"switchRequests"` // players waiting on approval to switch teams
+
+	Private             bool `json:"private"`            // new players need a passphrase to join
+	SpectatorsPrivate   bool `json:"spectatorsPrivate"`  // spectators have their own passphrase
+	SpectatorsDisabled  bool `json:"spectatorsDisabled"` // no displays or overlays can watch the game
+	passphrase          string
+	spectatorPassphrase string
 }
 
-func (g *Game) join(name string) (*Player, error) {
+// join adds a new player to the game or reconnects an existing one.  It also returns the sequence number of the
+// last message the player has already seen, the new connection sends everything after it
+func (g *Game) join(name, token, passphrase string, lastSeq uint64) (*Player, uint64, error) {
 	if name == "" {
-		return nil, fail.New("You must provide a name before joining")
-	}
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
-
-	if player, ok := g.Team1.player(name); ok {
-		if player.ping() {
-			return nil, fail.New("A player with the name " + name + " is already connected, please choose a new name")
-		}
-		return player, nil
+		return nil, 0, fail.New("You must provide a name before joining")
 	}
 
-	if player, ok := g.Team2.player(name); ok {
-		if player.ping() {
-			return nil, fail.New("A player with the name " + name + " is already connected, please choose a new name")
+	if player, ok := findPlayer(g, name); ok {
+		start := resumeFrom(player, lastSeq)
+		if err := rejoin(player, token); err != nil {
+			return nil, 0, err
 		}
-		return player, nil
+		sendSnapshot(g, player)
+		sendChatHistory(g, player)
+		return player, start, nil
 	}
 
 	// new player
 	if g.Stage != stagePregame {
-		return nil, fail.New("You cannot join a game in progress")
+		return nil, 0, fail.New("You cannot join a game in progress")
+	}
+
+	if err := checkPassphrase(g.passphrase, passphrase); err != nil {
+		return nil, 0, err
 	}
 
 	log.Printf("Player %s joined game %s", name, g.Code)
+	draftJoin(g, name)
 	if len(g.Team1.Players) <= len(g.Team2.Players) {
 		player := g.Team1.addNewPlayer(name, g)
 		if g.Leader == nil && len(g.Team1.Players) == 1 {
@@ -162,20 +191,20 @@ func (g *Game) join(name string) (*Player, error) {
 			g.Leader = player
 		}
 
-		return player, nil
+		issueToken(player)
+		sendSnapshot(g, player)
+		sendChatHistory(g, player)
+		return player, 0, nil
 	}
 
 	player := g.Team2.addNewPlayer(name, g)
-	return player, nil
+	issueToken(player)
+	sendSnapshot(g, player)
+	sendChatHistory(g, player)
+	return player, 0, nil
 }
 
 func (g *Game) setNamesPerPlayer(who *Player, num int) error {
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
-
 	if g.Stage != stagePregame {
 		return fail.New("The number of names per player cannot be set after the game has started")
 	}
@@ -196,28 +225,27 @@ func (g *Game) setNamesPerPlayer(who *Player, num int) error {
 	return nil
 }
 
-func (g *Game) updatePlayers() {
-	g.RLock()
-	defer g.RUnlock()
-	g.Team1.updatePlayers(g.gameState)
-	g.Team2.updatePlayers(g.gameState)
-}
-
-// same as method, except game lock is already managed
+// updatePlayers sends players and viewers whatever has changed in the game state since they were last updated.  The
+// event loop runs it after every event, so only call it directly to send a change before the event is finished
 func updatePlayers(g *Game) {
-	state := g.gameState
+	if patch, ok := g.playerStates.patch(g.gameState); ok {
+		g.Team1.sendMsg(patch)
+		g.Team2.sendMsg(patch)
+	}
+	updateViewers(g)
+}
 
-	g.Team1.updatePlayers(state)
-	g.Team2.updatePlayers(state)
+// sendSnapshot sends the full game state to a single player
+func sendSnapshot(g *Game, p *Player) {
+	snapshot, patch, changed := g.playerStates.snapshot(g.gameState)
+	if changed {
+		g.Team1.sendMsg(patch)
+		g.Team2.sendMsg(patch)
+	}
+	p.SendMsg(snapshot)
 }
 
 func (g *Game) startGame(who *Player) error {
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
-
 	if !who.isLeader() {
 		return fail.New("Only the %s can start the game", g.Leader.Name)
 	}
@@ -225,19 +253,19 @@ func (g *Game) startGame(who *Player) error {
 		return fail.New("The game has already started")
 	}
 
+	if g.Draft.Active {
+		return fail.New("The game cannot start until the draft is finished")
+	}
+
 	cleanPlayers(g)
 
-	if len(g.Team1.Players) < 2 || len(g.Team2.Players) < 2 {
+	if !teamsReady(g) {
 		// return to pregame and wait for players to join
 		return nil
 	}
 
 	g.Stage = stageSetup
 	g.startTimer(setupSecondsPerName*g.NamesPerPlayer, func() {
-		g.RLock()
-		playTimerSound(g, &g.Team1)
-		playTimerSound(g, &g.Team2)
-
 		startRound := true
 		for _, p := range g.Team1.Players {
 			if len(p.names()) < g.NamesPerPlayer {
@@ -254,14 +282,11 @@ func (g *Game) startGame(who *Player) error {
 				}
 			}
 		}
-		g.RUnlock()
 		if startRound {
 			// if all players have submitted the necessary names, end the timer early and start the round
-			g.stopTimer() // will start the round on the subsequent tick
+			stopTimer(g) // will start the round once the timer finishes
 		}
-		g.updatePlayers()
 	}, func() {
-		g.RLock()
 		// don't start the round if no one submitted names in time
 		startRound := false
 		for _, p := range g.Team1.Players {
@@ -278,16 +303,12 @@ func (g *Game) startGame(who *Player) error {
 				}
 			}
 		}
-		g.RUnlock()
-		if !startRound {
-			g.Lock()
+		if !startRound || !teamsReady(g) {
 			g.Stage = stagePregame
-			g.Unlock()
-			g.updatePlayers()
 			return
 		}
 
-		g.changeRound(1)
+		changeRound(g, 1)
 	}, func() {
 		g.Team1.playSound(soundTimerAlarm)
 		g.Team2.playSound(soundTimerAlarm)
@@ -297,20 +318,24 @@ func (g *Game) startGame(who *Player) error {
 	return nil
 }
 
-// IsDead tests if a game is no longer active and can be cleaned up
-func (g *Game) IsDead() bool {
-	g.RLock()
-	defer g.RUnlock()
+// teamsReady is whether both teams have enough players to take turns giving clues
+func teamsReady(g *Game) bool {
+	return len(g.Team1.Players) >= minTeamPlayers && len(g.Team2.Players) >= minTeamPlayers
+}
 
+// isDead tests if a game is no longer active and can be cleaned up, players who only just lost their connection are
+// given until the next check to come back
+func isDead(g *Game) bool {
 	if len(g.Team1.Players) == 0 && len(g.Team2.Players) == 0 {
 		return true
 	}
 
-	if !g.Team1.isDead() {
+	now := g.clock.Now()
+	if !g.Team1.isDead(now, pollStatus) {
 		return false
 	}
 
-	if !g.Team2.isDead() {
+	if !g.Team2.isDead(now, pollStatus) {
 		return false
 	}
 
@@ -321,97 +346,84 @@ func cleanPlayers(g *Game) {
 	g.Team1.cleanPlayers()
 	g.Team2.cleanPlayers()
 
-	if !g.Leader.ping() && len(g.Team1.Players) > 0 {
+	if !g.Leader.Connected && len(g.Team1.Players) > 0 {
 		g.Leader = g.Team1.Players[0]
 	}
 }
 
-func (g *Game) switchTeams(who *Player) {
-	g.Lock()
-	who.Lock()
-	defer func() {
-		g.Unlock()
-		who.Unlock()
-		g.updatePlayers()
-	}()
-
-	if g.Team1.removePlayer(who.Name) {
-		g.Team2.addExistingPlayer(who)
-	} else {
-		g.Team2.removePlayer(who.Name)
-		g.Team1.addExistingPlayer(who)
-	}
-}
-
-func (g *Game) stopTimer() {
-	g.Lock()
-	stopTimer(g)
-	g.Unlock()
-}
-
 func stopTimer(g *Game) {
 	if g.Timer.stop != nil {
 		g.Timer.stop <- true
-		g.Timer.Left = 0
-		g.Timer.durationLeft = 0
+		g.Timer.Deadline = 0
+		g.Timer.deadline = time.Time{}
 		g.Timer.stop = nil
+		g.Timer.cancel = nil
 	}
 }
 
-func playTimerSound(g *Game, team *Team) {
-	ratio := float64(g.Timer.Left) / float64(g.Timer.Seconds)
-	if g.Timer.durationLeft < 500*time.Millisecond {
-		// make sure the half second ticking sound doens't overlap with the timer alorm sound
-		return
-	}
-	if ratio <= .25 {
-		// tick every half second
-		team.playSound(soundTick)
-		return
+// timeLeft is how long the running timer has left, zero if no timer is running
+func timeLeft(g *Game) time.Duration {
+	if g.Timer.stop == nil {
+		return 0
 	}
-	if ratio <= .5 {
-		if g.Timer.Left == int(g.Timer.durationLeft.Round(time.Second)/time.Second) {
-			// tick every second
-			team.playSound(soundTick)
-		}
+	left := g.Timer.deadline.Sub(g.clock.Now())
+	if left < 0 {
+		return 0
 	}
+	return left
 }
 
+// startTimer starts the game timer, with each of the timer's callbacks run as an event on the game's loop.  Ticks
+// and timeouts are ignored if the timer has since been stopped or replaced.  Finish runs when the timer runs out or
+// is stopped, but a timer that's replaced by another is cancelled outright, since whatever it was timing is over
 func (g *Game) startTimer(seconds int, tick func(), finish func(), timeout func()) {
-	go func() {
-		g.Lock()
-		defer func() {
-			g.Unlock()
-			g.updatePlayers()
-		}()
-
-		g.Timer.Seconds = seconds
-		g.Timer.Left = seconds
-		g.Timer.durationLeft = time.Duration(g.Timer.Left * int(time.Second))
-
-		g.Timer.stop = startTimer(g.Timer.durationLeft, func(passed time.Duration) {
-			g.Lock()
-			g.Timer.durationLeft -= passed
-			g.Timer.Left = int(g.Timer.durationLeft / time.Second)
-			g.Unlock()
-			if tick != nil {
-				tick()
+
+	duration := time.Duration(seconds) * time.Second
+	g.Timer.Seconds = seconds
+	g.Timer.deadline = g.clock.Now().Add(duration)
+	g.Timer.Deadline = g.Timer.deadline.UnixMilli()
+
+	var stop chan bool
+	cancelled := false
+	current := func(event func()) func() {
+		if event == nil {
+			return nil
+		}
+		return func() {
+			g.do(func() {
+				if g.Timer.stop == stop {
+					event()
+				}
+			})
+		}
+	}
+
+	stop = startTimer(g.clock, duration, current(tick), func() {
+		g.do(func() {
+			if cancelled {
+				return
+			}
+			if g.Timer.stop == stop {
+				// don't clear out a timer that has since replaced this one
+				g.Timer.stop = nil
+				g.Timer.cancel = nil
+				g.Timer.Deadline = 0
+				g.Timer.deadline = time.Time{}
 			}
-		}, func() {
-			g.Lock()
-			g.Timer.stop = nil
-			g.Unlock()
 			if finish != nil {
 				finish()
 			}
-		}, timeout)
-	}()
+		})
+	}, current(timeout))
+	g.Timer.stop = stop
+	g.Timer.cancel = func() {
+		cancelled = true
+		stop <- true
+	}
 }
 
-func (g *Game) changeRound(round int) {
-	g.stopTimer() // stop timer incase previous round end early
-	g.Lock()
-	defer g.Unlock()
+func changeRound(g *Game, round int) {
+	stopTimer(g) // stop timer incase previous round end early
 
 	if g.Stage == stagePregame {
 		// game got reset
@@ -419,31 +431,25 @@ func (g *Game) changeRound(round int) {
 	}
 
 	g.Stage = stageRoundChange
-	updatePlayers(g)
+	g.SwitchRequests = nil
 	g.Team1.playSound(soundRoundEnd)
 	g.Team2.playSound(soundRoundEnd)
 
-	g.startTimer(10, g.updatePlayers, func() {
-		g.startRound(round)
+	g.startTimer(10, nil, func() {
+		startRound(g, round)
 	}, nil)
 }
 
-func (g *Game) startRound(round int) {
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
-
+func startRound(g *Game, round int) {
 	g.Stage = stagePlaying
 	g.Round = round
 	g.canSteal = false
+	fillTimeBank(g, round)
 	loadNames(g)
 	nextPlayerTurn(g)
 }
 
 func shuffleNames(g *Game) {
-	g.rand.Seed(time.Now().UnixNano())
 	g.rand.Shuffle(len(g.nameList), func(i, j int) {
 		g.nameList[i], g.nameList[j] = g.nameList[j], g.nameList[i]
 	})
@@ -464,37 +470,62 @@ func loadNames(g *Game) {
 }
 
 func nextPlayerTurn(g *Game) {
+	g.Stage = stagePlaying
+	shuffleNames(g)
+	g.clueGiverTrack.team1 = !g.clueGiverTrack.team1
+	if timeBankEmpty(g, g.clueGiverTrack.team1) {
+		// skip teams who have used up their time bank
+		g.clueGiverTrack.team1 = !g.clueGiverTrack.team1
+		if timeBankEmpty(g, g.clueGiverTrack.team1) {
+			outOfTime(g)
+			return
+		}
+	}
+
 	defer func() {
 		g.ClueGiver.playSound(soundNotify)
 		g.ClueGiver.SendMsg(Msg{Type: "startcheck"})
 	}()
 
-	g.Stage = stagePlaying
-	shuffleNames(g)
-	g.clueGiverTrack.team1 = !g.clueGiverTrack.team1
 	if g.clueGiverTrack.team1 {
-		g.clueGiverTrack.team1Index++
-		if g.clueGiverTrack.team1Index >= len(g.Team1.Players) {
-			g.clueGiverTrack.team1Index = 0
-		}
-		g.ClueGiver = g.Team1.Players[g.clueGiverTrack.team1Index]
-		return
+		g.ClueGiver = nextClueGiver(g, &g.Team1, &g.clueGiverTrack.team1Index, &g.clueGiverTrack.team1Bag)
+	} else {
+		g.ClueGiver = nextClueGiver(g, &g.Team2, &g.clueGiverTrack.team2Index, &g.clueGiverTrack.team2Bag)
 	}
 
-	g.clueGiverTrack.team2Index++
-	if g.clueGiverTrack.team2Index >= len(g.Team2.Players) {
-		g.clueGiverTrack.team2Index = 0
+	g.clueGiverTrack.turn++
+	g.ClueGiver.takeTurn(g.clueGiverTrack.turn)
+}
+
+// turnSummary is a summary of a single clue giver's turn
+type turnSummary struct {
+	Round     int    `json:"round"`
+	Team      int    `json:"team"`
+	ClueGiver string `json:"clueGiver"`
+	Guessed   int    `json:"guessed"`
+	Stolen    bool   `json:"stolen"` // whether the other team stole the last name
+}
+
+func startTurnSummary(g *Game) {
+	team := 2
+	if g.clueGiverTrack.team1 {
+		team = 1
 	}
-	g.ClueGiver = g.Team2.Players[g.clueGiverTrack.team2Index]
+	g.TurnSummaries = append(g.TurnSummaries, turnSummary{
+		Round:     g.Round,
+		Team:      team,
+		ClueGiver: g.ClueGiver.Name,
+	})
 }
 
-func (g *Game) startTurn(p *Player) error {
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
+func currentTurnSummary(g *Game) *turnSummary {
+	if len(g.TurnSummaries) == 0 {
+		return nil
+	}
+	return &g.TurnSummaries[len(g.TurnSummaries)-1]
+}
 
+func (g *Game) startTurn(p *Player) error {
 	if g.Stage != stagePlaying {
 		return nil
 	}
@@ -503,24 +534,22 @@ func (g *Game) startTurn(p *Player) error {
 		return nil
 	}
 
+
+	if !g.canSteal {
+		startTurnSummary(g)
+	}
 	g.canSteal = true
 	team := &g.Team1
 	if !g.clueGiverTrack.team1 {
 		team = &g.Team2
 	}
-	g.startTimer(secondsPerTurn, func() {
-		g.RLock()
-		playTimerSound(g, team)
-		g.RUnlock()
-		g.updatePlayers()
-	}, func() {
+	startTurnClock(g)
+	g.startTimer(turnSeconds(g), nil, func() {
+		stopTurnClock(g)
 		if g.canSteal {
-			g.steal()
+			steal(g)
 		} else {
-			g.Lock()
 			nextPlayerTurn(g)
-			g.updatePlayers()
-			g.Unlock()
 		}
 	}, func() {
 		team.playSound(soundTimerAlarm)
@@ -529,19 +558,17 @@ func (g *Game) startTurn(p *Player) error {
 	if len(g.nameList) == 0 {
 		return nil
 	}
-	g.Stats.nameTime = time.Now()
-	p.SendMsg(Msg{Type: "name", Data: p.game.nameList[0].name})
-
+	sendName(g)
 	return nil
 }
 
-func (g *Game) nextName(p *Player) error {
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
+// sendName sends the clue giver the current name, and starts timing how long it takes to guess
+func sendName(g *Game) {
+	g.Stats.nameTime = g.clock.Now()
+	g.ClueGiver.SendMsg(Msg{Type: "name", Data: g.nameList[0].name})
+}
 
+func (g *Game) nextName(p *Player) error {
 	if g.Stage != stagePlaying {
 		return nil
 	}
@@ -550,45 +577,67 @@ func (g *Game) nextName(p *Player) error {
 		return nil
 	}
 
-	if g.Timer.Left == 0 {
+	if timeLeft(g) == 0 {
 		return nil
 	}
 
-	updateNameStats(g, false)
+	nameGuessed(g)
+	return nil
+}
+
+// nameGuessed scores the current name for the clue giver's team and moves on to the next name
+func nameGuessed(g *Game) {
+	score(g, g.clueGiverTrack.team1, false, updateNameStats(g, false))
+	if turn := currentTurnSummary(g); turn != nil {
+		turn.Guessed++
+	}
 
 	g.nameList = g.nameList[1:]
 	if g.clueGiverTrack.team1 {
-		g.Stats.Team1Score++
 		g.Team1.playSound(soundScore)
 	} else {
-		g.Stats.Team2Score++
 		g.Team2.playSound(soundScore)
 	}
 
 	if len(g.nameList) == 0 {
+		stopTurnClock(g)
 		g.ClueGiver = nil
 		if g.Round == 3 {
-			go g.endGame() // run on a separate go routine to prevent deadlock
-			return nil
+			endGame(g)
+			return
 		}
-		go g.changeRound(g.Round + 1) // run on a separate go routine to prevent deadlock
+		changeRound(g, g.Round+1)
 
-		return nil
+		return
 	}
 
-	p.SendMsg(Msg{Type: "name", Data: g.nameList[0].name})
-	return nil
+	sendName(g)
 }
 
-func updateNameStats(g *Game, steal bool) {
+// updateNameStats records the current name being guessed, and returns how long it took to guess
+func updateNameStats(g *Game, steal bool) time.Duration {
 	if steal {
 		g.Stats.MostStolen.stats[g.ClueGiver.Name]++
 	} else {
 		g.Stats.BestClueGiver.stats[g.ClueGiver.Name]++
 	}
-	diff := time.Now().Sub(g.Stats.nameTime)
+	diff := g.clock.Now().Sub(g.Stats.nameTime)
 	name := g.nameList[0]
 
+	team := 1
+	if g.clueGiverTrack.team1 == steal {
+		team = 2
+	}
+	g.history = append(g.history, NameResult{
+		Name:      name.name,
+		Submitter: name.player,
+		Round:     g.Round,
+		ClueGiver: g.ClueGiver.Name,
+		Team:      team,
+		Stolen:    steal,
+		GuessTime: diff.Round(100 * time.Millisecond).Seconds(),
+	})
+
 	if diff > g.Stats.HardestName.guessTime {
 		g.Stats.HardestName.guessTime = diff
 		g.Stats.HardestName.Name = name.name
@@ -603,43 +652,26 @@ func updateNameStats(g *Game, steal bool) {
 		g.Stats.EasiestName.Round = g.Round
 		g.Stats.EasiestName.GuessTime = fmt.Sprintf("%9.1f seconds", diff.Round(time.Millisecond).Seconds())
 	}
+	return diff
 }
 
 // send final answer vote button to stealing team
 // if entire team responds final answer before timer runs out, then ClueGiver gets to
 // set if they got it right or not
-func (g *Game) steal() {
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
-
+func steal(g *Game) {
 	if g.Stage != stagePlaying {
 		return
 	}
 	g.Stage = stageStealing
 
-	g.Unlock()
-	// update to steal stage immediately
-	g.updatePlayers()
-	g.Lock()
+	// update to steal stage before the clue giver is asked to check the steal
+	updatePlayers(g)
 	team := &g.Team1
 	if g.clueGiverTrack.team1 {
 		team = &g.Team2
 	}
 
-	g.startTimer(secondsToSteal, func() {
-		g.RLock()
-		playTimerSound(g, team)
-		g.RUnlock()
-		g.updatePlayers()
-	}, nil, func() {
-		g.Lock()
-		defer func() {
-			g.Unlock()
-			g.updatePlayers()
-		}()
+	g.startTimer(secondsToSteal, nil, nil, func() {
 		team.playSound(soundTimerAlarm)
 		nextPlayerTurn(g)
 	})
@@ -648,12 +680,6 @@ func (g *Game) steal() {
 }
 
 func (g *Game) stealConfirm(p *Player, correct bool) error {
-	g.stopTimer()
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
 	if g.Stage != stageStealing {
 		return fail.New("Turn is not being stolen currently")
 	}
@@ -662,41 +688,41 @@ func (g *Game) stealConfirm(p *Player, correct bool) error {
 		return nil
 	}
 
+	stopTimer(g)
+	stealAnswered(g, correct)
+	return nil
+}
+
+// stealAnswered scores the current name for the stealing team if they got it right, and moves on to the next turn
+func stealAnswered(g *Game, correct bool) {
 	if correct {
-		updateNameStats(g, true)
+		score(g, !g.clueGiverTrack.team1, true, updateNameStats(g, true))
+		if turn := currentTurnSummary(g); turn != nil {
+			turn.Stolen = true
+		}
 		g.nameList = g.nameList[1:]
 		if g.clueGiverTrack.team1 {
-			g.Stats.Team2Score++
 			g.Team2.playSound(soundScore)
 		} else {
-			g.Stats.Team1Score++
 			g.Team1.playSound(soundScore)
 		}
 
 		if len(g.nameList) == 0 {
 			if g.Round == 3 {
-				go g.endGame() // run on a separate go routine to prevent deadlock
-				return nil
+				endGame(g)
+				return
 			}
-			go g.changeRound(g.Round + 1) // run on a separate go routine to prevent deadlock
+			changeRound(g, g.Round+1)
 
-			return nil
+			return
 		}
-		nextPlayerTurn(g)
-		return nil
 	}
 
 	nextPlayerTurn(g)
-	return nil
 }
 
-func (g *Game) endGame() {
-	g.stopTimer()
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
+func endGame(g *Game) {
+	stopTimer(g)
 	g.Stage = stageEnd
 	if g.Stats.Team1Score > g.Stats.Team2Score {
 		g.Stats.Winner = 1
@@ -722,16 +748,16 @@ func (g *Game) endGame() {
 		}
 	}
 
+	g.lastResults = newResults(g)
+	g.Rematch = true
+	archiveResults(g.lastResults)
+	g.Stats.Reactions = reactionAwards(g)
+	recordSeriesGame(g)
+
 	log.Printf("Game %s finished", g.Code)
 }
 
 func (g *Game) reset(p *Player, reason string) error {
-	g.Lock()
-	defer func() {
-		g.Unlock()
-		g.updatePlayers()
-	}()
-
 	if g.Stage != stageEnd {
 		return fail.New("Game has not yet ended")
 	}
@@ -746,19 +772,31 @@ func (g *Game) reset(p *Player, reason string) error {
 func reset(g *Game, reason string) {
 	stopTimer(g)
 	g.Stage = stagePregame
-	g.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
 	g.Round = 0
 	g.ClueGiver = nil
 	g.clueGiverTrack.team1 = false
 	g.clueGiverTrack.team1Index = -1
 	g.clueGiverTrack.team2Index = -1
+	g.clueGiverTrack.team1Bag = nil
+	g.clueGiverTrack.team2Bag = nil
+	g.clueGiverTrack.turn = 0
+	g.TimeBank.team1 = 0
+	g.TimeBank.team2 = 0
+	g.TimeBank.turnStart = time.Time{}
+	updateTimeBankLeft(g)
+	g.Team1.clearTurns()
+	g.Team2.clearTurns()
 	g.nameList = nil
+	g.history = nil
+	g.TurnSummaries = nil
 	g.Team1.clearNames()
 	g.Team2.clearNames()
 	g.canSteal = false
 	g.Stats.Winner = 0
 	g.Stats.Team1Score = 0
 	g.Stats.Team2Score = 0
+	g.Stats.Team1Breakdown = scoreBreakdown{Rounds: make([]int, 3)}
+	g.Stats.Team2Breakdown = scoreBreakdown{Rounds: make([]int, 3)}
 	g.Stats.BestClueGiver.Player = ""
 	g.Stats.BestClueGiver.Guesses = 0
 	g.Stats.BestClueGiver.stats = make(map[string]int)
@@ -773,6 +811,9 @@ func reset(g *Game, reason string) {
 	g.Stats.HardestName.Submitter = ""
 	g.Stats.HardestName.GuessTime = ""
 	g.Stats.HardestName.Round = 0
+	g.Stats.Reactions = nil
+	g.Stats.reactions = make(map[string]map[string]int)
+	nextSeriesGame(g)
 
 	if reason != "" {
 		g.Team1.sendNotification(reason)
@@ -0,0 +1,417 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// simulation plays through a game on a fake clock, sending requests the same way a player's connection would
+type simulation struct {
+	t       *testing.T
+	g       *Game
+	clock   *fakeClock
+	players map[string]*Player
+}
+
+// newSimulation starts a game with four connected players, a and c on team 1 and b and d on team 2, with a leading
+func newSimulation(t *testing.T, code string, seed int64) *simulation {
+	t.Helper()
+	s := &simulation{
+		t:       t,
+		clock:   newFakeClock(),
+		players: make(map[string]*Player),
+	}
+	s.g = newGame(code, s.clock, seed)
+	t.Cleanup(s.g.close)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		var p *Player
+		var err error
+		s.g.call(func() {
+			p, _, err = s.g.join(name, "", "", 0)
+		})
+		if err != nil {
+			t.Fatalf("Error joining %s: %s", name, err)
+		}
+		p.Connect()
+		s.players[name] = p
+	}
+	return s
+}
+
+// request runs a request from a player on the game's loop, and returns the game's error
+func (s *simulation) request(name, requestType string, data interface{}) error {
+	s.t.Helper()
+	r := Request{Type: requestType}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			s.t.Fatalf("Error encoding %s data: %s", requestType, err)
+		}
+		r.Data = raw
+	}
+
+	var err error
+	s.g.call(func() {
+		err = handleRequest(s.players[name], r)
+	})
+	return err
+}
+
+// must runs a request that is expected to succeed
+func (s *simulation) must(name, requestType string, data interface{}) {
+	s.t.Helper()
+	if err := s.request(name, requestType, data); err != nil {
+		s.t.Fatalf("%s request from %s failed: %s", requestType, name, err)
+	}
+}
+
+func (s *simulation) stage() string {
+	var stage string
+	s.g.call(func() {
+		stage = s.g.Stage
+	})
+	return stage
+}
+
+func (s *simulation) clueGiver() string {
+	name := ""
+	s.g.call(func() {
+		if s.g.ClueGiver != nil {
+			name = s.g.ClueGiver.Name
+		}
+	})
+	return name
+}
+
+// waitFor waits for the game to catch up with timers that have fired on the clock, since they're run as events
+// on their own go routines
+func (s *simulation) waitFor(what string, done func(g *Game) bool) {
+	s.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		finished := false
+		s.g.call(func() {
+			finished = done(s.g)
+		})
+		if finished {
+			return
+		}
+		if time.Now().After(deadline) {
+			s.t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (s *simulation) waitForStage(stage string) {
+	s.t.Helper()
+	s.waitFor("stage "+stage, func(g *Game) bool { return g.Stage == stage })
+}
+
+// expire runs the clock out to the current timer's deadline, and waits for the game to handle it
+func (s *simulation) expire() {
+	s.t.Helper()
+	var stop chan bool
+	var left time.Duration
+	s.g.call(func() {
+		stop = s.g.Timer.stop
+		left = timeLeft(s.g)
+	})
+	if stop == nil {
+		s.t.Fatalf("No timer is running in stage %s", s.stage())
+	}
+
+	s.clock.Advance(left)
+	s.waitFor("the timer to expire", func(g *Game) bool { return g.Timer.stop != stop })
+}
+
+// setup starts the game and has every player submit their names, which starts the first round
+func (s *simulation) setup() {
+	s.t.Helper()
+	s.must("a", "start", nil)
+	if stage := s.stage(); stage != stageSetup {
+		s.t.Fatalf("Game is in stage %s after starting, wanted %s", stage, stageSetup)
+	}
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		for i := 0; i < 3; i++ {
+			s.must(name, "addname", fmt.Sprintf("%s's name %d", name, i))
+		}
+	}
+
+	// setup checks whether everyone is done each time the timer polls
+	s.clock.Advance(timerPoll)
+	s.waitForStage(stageRoundChange)
+}
+
+// play plays out the rest of the game.  Every clue giver gets a couple of names before running out of time, and
+// the other team alternates between stealing the next name, missing it, and running out of time to answer
+func (s *simulation) play() {
+	s.t.Helper()
+	steals := 0
+	for turn := 0; ; turn++ {
+		if turn > 500 {
+			s.t.Fatalf("Game never ended, still in stage %s", s.stage())
+		}
+
+		switch stage := s.stage(); stage {
+		case stageRoundChange:
+			s.expire()
+		case stagePlaying:
+			giver := s.clueGiver()
+			s.must(giver, "startturn", nil)
+			for i := 0; i < 2 && s.stage() == stagePlaying; i++ {
+				s.clock.Advance(2 * time.Second)
+				s.must(giver, "nextname", nil)
+			}
+			if s.stage() == stagePlaying {
+				s.expire()
+			}
+		case stageStealing:
+			switch steals % 3 {
+			case 0:
+				s.must(s.clueGiver(), "stealyes", nil)
+			case 1:
+				s.must(s.clueGiver(), "stealno", nil)
+			default:
+				s.expire()
+			}
+			steals++
+		case stageEnd:
+			return
+		default:
+			s.t.Fatalf("Unexpected stage %s", stage)
+		}
+	}
+}
+
+func TestFullGame(t *testing.T) {
+	s := newSimulation(t, "TFUL", 1)
+	s.setup()
+	s.play()
+
+	s.g.call(func() {
+		g := s.g
+		if g.Round != 3 {
+			t.Errorf("Game ended in round %d, wanted 3", g.Round)
+		}
+		if len(g.nameList) != 0 {
+			t.Errorf("Game ended with %d names left", len(g.nameList))
+		}
+
+		guessed := 0
+		stolen := 0
+		for _, n := range g.history {
+			guessed++
+			if n.Stolen {
+				stolen++
+			}
+		}
+		if guessed != 3*12 {
+			t.Errorf("%d names were guessed, wanted every name guessed each round", guessed)
+		}
+		if stolen == 0 {
+			t.Errorf("No names were stolen")
+		}
+		if g.Stats.Team1Score+g.Stats.Team2Score == 0 {
+			t.Errorf("Neither team scored")
+		}
+		if g.lastResults == nil {
+			t.Errorf("Results weren't recorded when the game ended")
+		}
+	})
+}
+
+func TestResetAndRematch(t *testing.T) {
+	s := newSimulation(t, "TRST", 2)
+
+	if err := s.request("a", "reset", nil); err == nil {
+		t.Fatalf("Game was reset before it ended")
+	}
+
+	s.setup()
+	s.play()
+
+	if err := s.request("b", "reset", nil); err == nil {
+		t.Fatalf("Only the leader should be able to reset the game")
+	}
+	s.must("a", "reset", nil)
+
+	s.g.call(func() {
+		g := s.g
+		if g.Stage != stagePregame {
+			t.Errorf("Game is in stage %s after a reset, wanted %s", g.Stage, stagePregame)
+		}
+		if g.Round != 0 || g.ClueGiver != nil || g.Timer.stop != nil {
+			t.Errorf("Game wasn't cleared by the reset: round %d, clue giver %v, timer running %t", g.Round,
+				g.ClueGiver, g.Timer.stop != nil)
+		}
+		if len(g.history) != 0 || g.Stats.Team1Score != 0 || g.Stats.Team2Score != 0 {
+			t.Errorf("Scores from the last game weren't cleared by the reset")
+		}
+		for _, p := range append(g.Team1.Players, g.Team2.Players...) {
+			if len(p.Names) != 0 {
+				t.Errorf("%s still has names from the last game", p.Name)
+			}
+		}
+	})
+
+	// the same players can play again
+	s.setup()
+	s.play()
+}
+
+func TestSameSeedSameGame(t *testing.T) {
+	play := func(code string) []NameResult {
+		s := newSimulation(t, code, 42)
+		s.setup()
+		s.play()
+
+		var history []NameResult
+		s.g.call(func() {
+			history = append(history, s.g.history...)
+		})
+		return history
+	}
+
+	first := play("TSD1")
+	second := play("TSD2")
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("Games with the same seed played out differently:\n%v\n%v", first, second)
+	}
+}
+
+func TestSwitchTeamsDuringSetup(t *testing.T) {
+	s := newSimulation(t, "TSWT", 3)
+	s.must("a", "switchapproval", true)
+	s.must("b", "switchteams", nil)
+	s.must("a", "start", nil)
+
+	if err := s.request("d", "switchteams", nil); err == nil {
+		t.Fatalf("A player switched teams during setup, leaving their team with one player")
+	}
+	if err := s.request("a", "approveswitch", "b"); err == nil {
+		t.Fatalf("A switch was approved during setup, leaving the team with one player")
+	}
+
+	s.g.call(func() {
+		if len(s.g.Team1.Players) != 2 || len(s.g.Team2.Players) != 2 {
+			t.Fatalf("Teams changed during setup: %d and %d players", len(s.g.Team1.Players),
+				len(s.g.Team2.Players))
+		}
+	})
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		for i := 0; i < 3; i++ {
+			s.must(name, "addname", fmt.Sprintf("%s's name %d", name, i))
+		}
+	}
+	s.clock.Advance(timerPoll)
+	s.waitForStage(stageRoundChange)
+	s.play()
+}
+
+func TestSetupWithoutEnoughPlayers(t *testing.T) {
+	s := newSimulation(t, "TSMP", 4)
+	s.must("a", "start", nil)
+	s.must("a", "addname", "a's name")
+
+	// a player leaving during setup can't leave a team with no one to take turns
+	s.g.call(func() {
+		s.g.Team2.removePlayer("d")
+	})
+	s.expire()
+
+	if stage := s.stage(); stage != stagePregame {
+		t.Fatalf("Game is in stage %s after setup with a short team, wanted %s", stage, stagePregame)
+	}
+}
+
+func TestGuessTimes(t *testing.T) {
+	s := newSimulation(t, "TGTM", 5)
+	s.setup()
+	s.expire()
+	s.waitForStage(stagePlaying)
+
+	giver := s.clueGiver()
+	s.must(giver, "startturn", nil)
+
+	times := []time.Duration{3 * time.Second, 5 * time.Second, 1500 * time.Millisecond}
+	for _, d := range times {
+		s.clock.Advance(d)
+		s.must(giver, "nextname", nil)
+	}
+
+	s.g.call(func() {
+		if len(s.g.history) != len(times) {
+			t.Fatalf("%d names guessed, wanted %d", len(s.g.history), len(times))
+		}
+		for i := range times {
+			if got := s.g.history[i].GuessTime; got != times[i].Seconds() {
+				t.Errorf("Name %d took %.1f seconds to guess, wanted %.1f", i, got, times[i].Seconds())
+			}
+		}
+	})
+}
+
+func TestStartTurnTwice(t *testing.T) {
+	s := newSimulation(t, "TDBL", 6)
+	s.setup()
+	s.expire()
+	s.waitForStage(stagePlaying)
+
+	giver := s.clueGiver()
+	s.must(giver, "startturn", nil)
+	s.clock.Advance(10 * time.Second)
+	s.must(giver, "startturn", nil)
+
+	s.g.call(func() {
+		if left := timeLeft(s.g); left != (secondsPerTurn-10)*time.Second {
+			t.Fatalf("Starting the turn again changed the time left to %s", left)
+		}
+		if len(s.g.TurnSummaries) != 1 {
+			t.Fatalf("Starting the turn again added a turn summary, %d summaries", len(s.g.TurnSummaries))
+		}
+	})
+
+	// the turn ends once, when it's supposed to
+	s.clock.Advance((secondsPerTurn - 11) * time.Second)
+	s.g.call(func() {})
+	if stage := s.stage(); stage != stagePlaying {
+		t.Fatalf("Turn ended early, game is in stage %s", stage)
+	}
+	s.expire()
+	if stage := s.stage(); stage != stageStealing {
+		t.Fatalf("Game is in stage %s after the turn ran out, wanted %s", stage, stageStealing)
+	}
+}
+
+func TestReplacedTimerDoesNotFinish(t *testing.T) {
+	s := newSimulation(t, "TRPL", 7)
+	finished := make(chan string, 2)
+	s.g.call(func() {
+		s.g.startTimer(10, nil, func() { finished <- "first" }, nil)
+		s.g.startTimer(20, nil, func() { finished <- "second" }, nil)
+	})
+
+	s.clock.Advance(10 * time.Second)
+	s.g.call(func() {})
+	s.expire()
+
+	if got := <-finished; got != "second" {
+		t.Fatalf("The %s timer finished, wanted only the timer that replaced it", got)
+	}
+	select {
+	case got := <-finished:
+		t.Fatalf("The %s timer also finished", got)
+	default:
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+const maxTypedLength = 200 // longest guess or clue that can be typed
+
+// typedMsg is a typed guess or clue relayed to other players
+type typedMsg struct {
+	Player  string `json:"player"`
+	Text    string `json:"text"`
+	Correct bool   `json:"correct,omitempty"`
+}
+
+func (g *Game) setTypedGuesses(who *Player, typed bool) error {
+	if g.Stage != stagePregame {
+		return fail.New("Typed guesses cannot be changed after the game has started")
+	}
+
+	if !who.isLeader() {
+		return fail.New("Only game leaders can change typed guesses")
+	}
+
+	g.TypedGuesses = typed
+	return nil
+}
+
+// guess checks a typed guess against the current name.  During a turn, the clue giver's team can guess as
+// many times as they like, while stealing the opposing team gets one guess.
+func (g *Game) guess(p *Player, guess string) error {
+	if !g.TypedGuesses {
+		return fail.New("Typed guesses are not turned on for this game")
+	}
+
+	guess = strings.TrimSpace(guess)
+	if guess == "" {
+		return nil
+	}
+
+	if len(guess) > maxTypedLength {
+		return fail.New("Guesses can't be longer than %d characters", maxTypedLength)
+	}
+
+	if g.ClueGiver == nil || len(g.nameList) == 0 || p.Name == g.ClueGiver.Name {
+		return nil
+	}
+
+	guessingTeam1 := onTeam1(g, p)
+	correct := namesMatch(guess, g.nameList[0].name)
+	msg := Msg{Type: "guess", Data: typedMsg{Player: p.Name, Text: guess, Correct: correct}}
+
+	switch g.Stage {
+	case stagePlaying:
+		if guessingTeam1 != g.clueGiverTrack.team1 {
+			return fail.New("It's not your team's turn")
+		}
+		if !g.canSteal || timeLeft(g) == 0 {
+			// turn hasn't started yet or is already over
+			return nil
+		}
+
+		clueTeam(g).sendMsg(msg)
+		if correct {
+			nameGuessed(g)
+		}
+	case stageStealing:
+		if guessingTeam1 == g.clueGiverTrack.team1 {
+			return fail.New("Only the other team can steal")
+		}
+
+		g.Team1.sendMsg(msg)
+		g.Team2.sendMsg(msg)
+		stopTimer(g)
+		stealAnswered(g, correct)
+	}
+
+	return nil
+}
+
+// clue relays a typed clue from the clue giver to their team, enforcing the current round's rules
+func (g *Game) clue(p *Player, clue string) error {
+	if !g.TypedGuesses {
+		return fail.New("Typed clues are not turned on for this game")
+	}
+
+	if g.Stage != stagePlaying || g.ClueGiver == nil || g.ClueGiver.Name != p.Name || len(g.nameList) == 0 ||
+		!g.canSteal {
+		return nil
+	}
+
+	clue = strings.TrimSpace(clue)
+	if clue == "" {
+		return nil
+	}
+
+	if len(clue) > maxTypedLength {
+		return fail.New("Clues can't be longer than %d characters", maxTypedLength)
+	}
+
+	switch g.Round {
+	case 2:
+		return fail.New("No words allowed this round, act out your clues on camera")
+	case 3:
+		if len(strings.Fields(clue)) > 1 {
+			return fail.New("Only one word clues are allowed this round")
+		}
+	}
+
+	for _, word := range strings.Fields(normalizeName(g.nameList[0].name)) {
+		for _, clueWord := range strings.Fields(normalizeName(clue)) {
+			if clueWord == word {
+				return fail.New("Your clue can't include part of the name")
+			}
+		}
+	}
+
+	clueTeam(g).sendMsg(Msg{Type: "clue", Data: typedMsg{Player: p.Name, Text: clue}})
+	return nil
+}
+
+// clueTeam is the team of the current clue giver
+func clueTeam(g *Game) *Team {
+	if g.clueGiverTrack.team1 {
+		return &g.Team1
+	}
+	return &g.Team2
+}
+
+func onTeam1(g *Game, p *Player) bool {
+	_, ok := g.Team1.player(p.Name)
+	return ok
+}
+
+// namesMatch is whether a guess is close enough to a name to count, allowing for typos in longer names
+func namesMatch(guess, name string) bool {
+	guess = normalizeName(guess)
+	name = normalizeName(name)
+	if guess == "" {
+		return false
+	}
+	if guess == name {
+		return true
+	}
+
+	allowed := 0
+	switch length := len([]rune(name)); {
+	case length > 12:
+		allowed = 2
+	case length > 5:
+		allowed = 1
+	}
+
+	return levenshtein(guess, name) <= allowed
+}
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c", "ý", "y", "ÿ", "y", "ß", "ss",
+)
+
+// normalizeName lowercases a name, strips accents and punctuation, and removes a leading "the"
+func normalizeName(name string) string {
+	name = accents.Replace(strings.ToLower(name))
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		if unicode.IsSpace(r) || r == '-' {
+			return ' '
+		}
+		return -1
+	}, name)
+
+	words := strings.Fields(name)
+	if len(words) > 1 && words[0] == "the" {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// levenshtein is the number of single character edits needed to turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+// Each game runs a single event loop which owns all of the game's state.  Player commands, timer callbacks, and
+// anything outside of the game that needs to read its state are queued as events and run one at a time, in the
+// order they arrive, so nothing in a game needs to be locked.
+//
+// Events run on the loop, so they must never queue another event with do or call and wait on it, the loop would
+// be waiting on itself.  Anything an event needs to do to the game it can do directly.
+
+// run processes events until the game is closed, and sends players whatever changed after each one
+func (g *Game) run() {
+	for {
+		select {
+		case event := <-g.events:
+			event()
+			updatePlayers(g)
+		case <-g.done:
+			return
+		}
+	}
+}
+
+// do queues an event on the game's loop without waiting for it to run.  Events queued after the game is closed
+// are dropped
+func (g *Game) do(event func()) {
+	select {
+	case g.events <- event:
+	case <-g.done:
+	}
+}
+
+// call runs an event on the game's loop and waits for it to finish.  Returns false if the game is closed and the
+// event never ran
+func (g *Game) call(event func()) bool {
+	finished := make(chan struct{})
+	select {
+	case g.events <- func() {
+		defer close(finished)
+		event()
+	}:
+	case <-g.done:
+		return false
+	}
+	<-finished
+	return true
+}
+
+// close stops the game's event loop
+func (g *Game) close() {
+	close(g.done)
+}
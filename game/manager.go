@@ -37,30 +37,49 @@ func New(ipAddress string) (*Game, error) {
 	if err != nil {
 		return nil, err
 	}
-	rand.Seed(time.Now().UnixNano())
 	code := generateCode(4)
 	manager.Lock()
 	defer manager.Unlock()
 
+	g := newGame(code, systemClock{}, time.Now().UnixNano())
+
+	time.AfterFunc(pollStatus, func() { cleanGame(g) })
+
+	manager.games = append(manager.games, g)
+
+	log.Printf("Game %s created", g.Code)
+	return g, nil
+}
+
+// newGame sets up a game and starts its event loop.  All of the game's time comes from the clock, and all of its
+// shuffling and random picks from the seed
+func newGame(code string, clock Clock, seed int64) *Game {
 	g := &Game{
 		gameState: gameState{
 			Code:           code,
 			NamesPerPlayer: 3,
 			Stage:          stagePregame,
+			Series:         series{Games: 1},
+			Rotation:       rotationRoundRobin,
+			TimeBank:       timeBank{Scope: timeBankRound},
+			Scoring:        defaultScoring(),
 		},
+		clock:  clock,
+		rand:   rand.New(rand.NewSource(seed)),
+		events: make(chan func()),
+		done:   make(chan struct{}),
 	}
 	reset(g, "")
-
-	time.AfterFunc(pollStatus, func() { cleanGame(g) })
-
-	manager.games = append(manager.games, g)
-
-	log.Printf("Game %s created", g.Code)
-	return g, nil
+	go g.run()
+	return g
 }
 
 func cleanGame(g *Game) {
-	if g.IsDead() {
+	dead := true
+	g.call(func() {
+		dead = isDead(g)
+	})
+	if dead {
 		removeGame(g)
 		return
 	}
@@ -81,18 +100,31 @@ func Find(code string) (*Game, bool) {
 	return nil, false
 }
 
-// Join allows a player to join a game in progress
-func Join(code, name string) (*Player, error) {
-	g, ok := Find(code)
+// Join allows a player to join a game in progress.  Reconnecting as an existing player requires the rejoin token
+// they were sent when they first joined, and new players need the game's passphrase if it has one.  Along with
+// the player, it returns the sequence number the connection should start sending the player's messages after
+func Join(req JoinRequest, ipAddress string) (*Player, uint64, error) {
+	g, ok := Find(req.Code)
 	if !ok {
-		return nil, fail.NotFound("Invalid Game code, try again")
+		return nil, 0, fail.NotFound("Invalid Game code, try again")
 	}
-	player, err := g.join(name)
+	err := passphraseAttempt(ipAddress, g.Code)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	var player *Player
+	var start uint64
+	err = fail.NotFound("Invalid Game code, try again")
+	g.call(func() {
+		player, start, err = g.join(req.Name, req.Token, req.Passphrase, req.LastSeq)
+	})
+	err = passphraseResult(err, ipAddress, g.Code)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return player, nil
+	return player, start, nil
 }
 
 func removeGame(g *Game) {
@@ -102,6 +134,8 @@ func removeGame(g *Game) {
 	for i := range manager.games {
 		if manager.games[i].Code == g.Code {
 			manager.games = append(manager.games[:i], manager.games[i+1:]...)
+			g.close()
+			expireResults(g.Code)
 			log.Printf("Removing game %s", g.Code)
 			return
 		}
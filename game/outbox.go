@@ -0,0 +1,122 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const outboxSize = 256 // most unacknowledged messages held for a client before the oldest are dropped
+
+// droppedMessages counts messages that were dropped from an outbox before they were ever written to a client
+var droppedMessages atomic.Int64
+
+// DroppedMessages is how many messages have been dropped from outboxes before they were ever written to a client
+func DroppedMessages() int64 {
+	return droppedMessages.Load()
+}
+
+// outbox numbers every message sent to a player or viewer, and holds on to them until they're acknowledged.  Each
+// connection writes out messages in sequence from the outbox, so a player who reconnects picks up right after the
+// last message they saw, and nothing sent while they were disconnected is lost.  The outbox is bounded, so a client
+// that stops reading can't hold on to messages forever
+type outbox struct {
+	sync.Mutex
+	seq      uint64
+	written  uint64        // last message written to a connection
+	dropped  uint64        // last message dropped, either acknowledged or pushed out of a full outbox
+	messages []Msg         // held messages, oldest first
+	ready    chan struct{} // closed and replaced whenever a message is added
+}
+
+func newOutbox() *outbox {
+	return &outbox{ready: make(chan struct{})}
+}
+
+func (o *outbox) add(msg Msg) {
+	o.Lock()
+	defer o.Unlock()
+
+	o.seq++
+	msg.Seq = o.seq
+	if msg.Type == "state" {
+		o.coalesce()
+	}
+	o.messages = append(o.messages, msg)
+
+	if len(o.messages) > outboxSize {
+		full := o.messages[:len(o.messages)-outboxSize]
+		for i := range full {
+			if full[i].Seq > o.written {
+				droppedMessages.Add(1)
+			}
+		}
+		o.dropped = full[len(full)-1].Seq
+		o.messages = o.messages[len(full):]
+	}
+
+	close(o.ready)
+	o.ready = make(chan struct{})
+}
+
+// coalesce removes any state updates that haven't been written yet, a new full state makes them stale
+func (o *outbox) coalesce() {
+	held := o.messages[:0]
+	for _, msg := range o.messages {
+		if msg.Seq > o.written && (msg.Type == "state" || msg.Type == "patch") {
+			continue
+		}
+		held = append(held, msg)
+	}
+	o.messages = held
+}
+
+// since returns the messages after the passed in sequence number, along with a channel that is closed when the
+// next message is added.  Returns false if some of the messages have already been dropped and can't be replayed
+func (o *outbox) since(seq uint64) ([]Msg, <-chan struct{}, bool) {
+	o.Lock()
+	defer o.Unlock()
+
+	if seq > o.seq || seq < o.dropped {
+		return nil, o.ready, false
+	}
+
+	i := sort.Search(len(o.messages), func(i int) bool { return o.messages[i].Seq > seq })
+	msgs := make([]Msg, len(o.messages)-i)
+	copy(msgs, o.messages[i:])
+	return msgs, o.ready, true
+}
+
+// wrote records that a connection has written every message up to the passed in sequence number
+func (o *outbox) wrote(seq uint64) {
+	o.Lock()
+	defer o.Unlock()
+
+	if seq > o.written {
+		o.written = seq
+	}
+}
+
+// ack drops every message up to and including the passed in sequence number, the client has already seen them
+func (o *outbox) ack(seq uint64) {
+	o.Lock()
+	defer o.Unlock()
+
+	if seq > o.seq || seq <= o.dropped {
+		return
+	}
+
+	i := sort.Search(len(o.messages), func(i int) bool { return o.messages[i].Seq > seq })
+	o.messages = o.messages[i:]
+	o.dropped = seq
+}
+
+func (o *outbox) last() uint64 {
+	o.Lock()
+	defer o.Unlock()
+	return o.seq
+}
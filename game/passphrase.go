@@ -0,0 +1,138 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"crypto/subtle"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+const maxPassphraseLength = 100
+
+var errWrongPassphrase = fail.New("Wrong passphrase for this game")
+
+// passphraseRateDelay slows down wrong passphrase attempts from the same ip address, so private games can't be
+// brute forced
+var passphraseRateDelay = &RateDelay{
+	Type:   "passphrase",
+	Limit:  5,
+	Delay:  2 * time.Second,
+	Period: 5 * time.Minute,
+	Max:    30 * time.Second,
+}
+
+func (g *Game) setPassphrase(who *Player, passphrase string) error {
+	err := canChangePassphrase(g, who, passphrase)
+	if err != nil {
+		return err
+	}
+
+	g.passphrase = strings.TrimSpace(passphrase)
+	g.Private = g.passphrase != ""
+	log.Printf("Game %s private: %t", g.Code, g.Private)
+	return nil
+}
+
+func (g *Game) setSpectatorPassphrase(who *Player, passphrase string) error {
+	err := canChangePassphrase(g, who, passphrase)
+	if err != nil {
+		return err
+	}
+
+	g.spectatorPassphrase = strings.TrimSpace(passphrase)
+	g.SpectatorsPrivate = g.spectatorPassphrase != ""
+	return nil
+}
+
+func (g *Game) setSpectatorsDisabled(who *Player, disabled bool) error {
+	err := canChangePassphrase(g, who, "")
+	if err != nil {
+		return err
+	}
+
+	g.SpectatorsDisabled = disabled
+	if disabled {
+		for _, v := range g.viewers {
+			v.SendMsg(ErrorMsg(fail.New("Spectators have been disabled for this game")))
+		}
+		g.viewers = nil
+	}
+	return nil
+}
+
+func canChangePassphrase(g *Game, who *Player, passphrase string) error {
+	if g.Stage != stagePregame {
+		return fail.New("Passphrases cannot be changed after the game has started")
+	}
+
+	if !who.isLeader() {
+		return fail.New("Only game leaders can change passphrases")
+	}
+
+	if len(passphrase) > maxPassphraseLength {
+		return fail.New("Passphrases can't be longer than %d characters", maxPassphraseLength)
+	}
+	return nil
+}
+
+// checkPassphrase checks a passphrase against the expected one, an empty expected passphrase lets anyone in
+func checkPassphrase(expected, passphrase string) error {
+	if expected == "" {
+		return nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.TrimSpace(passphrase))) != 1 {
+		return errWrongPassphrase
+	}
+	return nil
+}
+
+// checkSpectator checks whether a spectator can watch the game.  Spectators use their own passphrase if one is
+// set, otherwise they need the same passphrase as players
+func checkSpectator(g *Game, passphrase string) error {
+	if g.SpectatorsDisabled {
+		return fail.New("Spectators are not allowed in this game")
+	}
+
+	if g.spectatorPassphrase != "" {
+		return checkPassphrase(g.spectatorPassphrase, passphrase)
+	}
+	return checkPassphrase(g.passphrase, passphrase)
+}
+
+// passphraseAttempt is charged against the ip address before every attempt to get into a game, so passphrases can't
+// be guessed any faster than the rate limit allows, no matter how many connections the guesses are spread across.
+// Once the limit is reached, the ip address is refused whether or not their passphrase is right
+func passphraseAttempt(ipAddress, code string) error {
+	_, err := passphraseRateDelay.Attempt(passphraseRateID(ipAddress, code))
+	return err
+}
+
+// passphraseResult gives back the attempt charged by passphraseAttempt unless it was a wrong passphrase, so only wrong
+// guesses count against the ip address
+func passphraseResult(err error, ipAddress, code string) error {
+	if err != errWrongPassphrase {
+		passphraseRateDelay.Refund(passphraseRateID(ipAddress, code))
+	}
+	return err
+}
+
+// passphraseRateID limits guesses per game, so joining other games can't be used to earn back wrong guesses
+func passphraseRateID(ipAddress, code string) string {
+	return ipAddress + "/" + code
+}
+
+// HasPassphrase is whether a passphrase is needed for new players to join the game
+func (g *Game) HasPassphrase() bool {
+	private := false
+	g.call(func() {
+		private = g.Private
+	})
+	return private
+}
@@ -6,25 +6,22 @@ package game
 
 import (
 	"encoding/json"
-	"log"
 	"strings"
-	"sync"
-	"time"
+	"sync/atomic"
 
 	"github.com/timshannon/threenamesinahat/fail"
 )
 
-const clientTimeout = 3 * time.Second
-
 // Player keeps track of a given player as well as is the communication channel
 type Player struct {
-	sync.RWMutex
 	playerState
 
-	chanPing chan bool
+	token    string       // secret required to rejoin as this player
+	commands chan Request // requests waiting to be run on the game's loop
+	outbox   *outbox      // messages sent to the player
+	seen     atomic.Int64 // unix milliseconds the player's connection was last heard from
 
-	Send    chan Msg `json:"-"`
-	Receive chan Msg `json:"-"`
+	Receive chan Request `json:"-"`
 
 	game *Game
 }
@@ -32,6 +29,13 @@ type Player struct {
 type playerState struct {
 	Name  string   `json:"name"`
 	Names []string `json:"names"`
+	Turns int      `json:"turns"` // how many turns they've had giving clues this game
+
+	Connected bool  `json:"connected"`
+	LastSeen  int64 `json:"lastSeen"` // unix milliseconds, updated whenever the player connects or disconnects
+
+	lastTurn    int // the game turn of the last time they gave clues
+	connections int // open connections, there can briefly be more than one while a player reconnects
 }
 
 func newPlayer(name string, game *Game) *Player {
@@ -39,111 +43,256 @@ func newPlayer(name string, game *Game) *Player {
 		playerState: playerState{
 			Name: name,
 		},
-		Send:     make(chan Msg, 5),
-		Receive:  make(chan Msg, 5),
-		chanPing: make(chan bool),
+		outbox:   newOutbox(),
+		Receive:  make(chan Request, 5),
+		commands: make(chan Request, 5),
 		game:     game,
 	}
 
 	go recieve(p)
+	go runCommands(p)
 
 	return p
 
 }
 
+// recieve handles acks and clock syncs right away, since they only affect the player's connection, and passes
+// everything else on to be run on the loop in the order it was received
 func recieve(p *Player) {
-	for msg := range p.Receive {
-		go func(m Msg) {
-			switch strings.ToLower(m.Type) {
-			case "pong":
-				p.chanPing <- true
-			case "namesperplayer":
-				if num, ok := m.Data.(float64); ok {
-					p.ok(p.game.setNamesPerPlayer(p, int(num)))
-				} else {
-					p.ok(fail.New("Invalid data type for namesperplayer. Got %T wanted float64", m.Data))
-				}
-			case "start":
-				p.ok(p.game.startGame(p))
-			case "switchteams":
-				p.game.switchTeams(p)
-			case "addname":
-				if name, ok := m.Data.(string); ok {
-					p.ok(p.addName(name))
-				} else {
-					p.ok(fail.New("Invalid data type for addname.  Got %T wanted string", m.Data))
-				}
-			case "removename":
-				if name, ok := m.Data.(string); ok {
-					p.ok(p.removeName(name))
-				} else {
-					p.ok(fail.New("Invalid data type for removename.  Got %T wanted string", m.Data))
-				}
-			case "startturn":
-				p.ok(p.game.startTurn(p))
-			case "nextname":
-				p.ok(p.game.nextName(p))
-			case "stealyes":
-				p.ok(p.game.stealConfirm(p, true))
-			case "stealno":
-				p.ok(p.game.stealConfirm(p, false))
-			case "reset":
-				p.ok(p.game.reset(p, ""))
-			case "requestupdate":
-				p.update(p.game.gameState)
-			default:
-				p.ok(fail.New("%s is an invalid message type", m.Type))
+	defer close(p.commands)
+	for {
+		var req Request
+		select {
+		case req = <-p.Receive:
+		case <-p.game.done:
+			return
+		}
+
+		switch strings.ToLower(req.Type) {
+		case "ack":
+			var seq uint64
+			if !p.ok(req.DecodeData(&seq)) {
+				p.outbox.ack(seq)
+			}
+		case "timesync":
+			// answered right away, time spent waiting on the loop would throw off the client's clock
+			msg, err := timeSyncMsg(req)
+			if !p.ok(err) {
+				p.SendMsg(msg)
 			}
-		}(msg)
+		default:
+			select {
+			case p.commands <- req:
+			case <-p.game.done:
+				return
+			}
+		}
 	}
 }
 
-func (p *Player) ok(err error) bool {
-	if err != nil {
-		if fail.IsFailure(err) {
-			p.SendMsg(Msg{
-				Type: "error",
-				Data: err.Error(),
-			})
-		} else {
-			log.Printf("Internal Error: %s", err)
-			p.SendMsg(Msg{
-				Type: "error",
-				Data: "An internal error has occured, please start a new game",
-			})
-		}
-		return true
+// runCommands runs a player's requests on the game's loop one at a time, until the game is closed
+func runCommands(p *Player) {
+	for req := range p.commands {
+		r := req
+		p.game.do(func() {
+			p.ok(handleRequest(p, r))
+		})
 	}
-	return false
 }
 
-func (p *Player) update(state gameState) {
-	p.SendMsg(Msg{
-		Type: "state",
-		Data: state,
-	})
-}
+// handleRequest decodes a request's data into the type expected for the request type, and passes it on to the game
+func handleRequest(p *Player, r Request) error {
+	g := p.game
 
-func (p *Player) ping() bool {
-	go func() { p.SendMsg(Msg{Type: "ping"}) }()
+	switch strings.ToLower(r.Type) {
+	case "namesperplayer":
+		var num int
+		if err := r.DecodeData(&num); err != nil {
+			return err
+		}
+		return g.setNamesPerPlayer(p, num)
+	case "seriesgames":
+		var games int
+		if err := r.DecodeData(&games); err != nil {
+			return err
+		}
+		return g.setSeriesGames(p, games)
+	case "seriesshuffle":
+		var shuffle bool
+		if err := r.DecodeData(&shuffle); err != nil {
+			return err
+		}
+		return g.setSeriesShuffle(p, shuffle)
+	case "rotation":
+		var rotation string
+		if err := r.DecodeData(&rotation); err != nil {
+			return err
+		}
+		return g.setRotation(p, rotation)
+	case "rotationorder":
+		var order []string
+		if err := r.DecodeData(&order); err != nil {
+			return err
+		}
+		return g.setRotationOrder(p, order)
+	case "timebank":
+		var seconds int
+		if err := r.DecodeData(&seconds); err != nil {
+			return err
+		}
+		return g.setTimeBank(p, seconds)
+	case "timebankscope":
+		var scope string
+		if err := r.DecodeData(&scope); err != nil {
+			return err
+		}
+		return g.setTimeBankScope(p, scope)
+	case "scoring":
+		var s scoring
+		if err := r.DecodeData(&s); err != nil {
+			return err
+		}
+		return g.setScoring(p, s)
+	case "typedguesses":
+		var typed bool
+		if err := r.DecodeData(&typed); err != nil {
+			return err
+		}
+		return g.setTypedGuesses(p, typed)
+	case "passphrase":
+		var passphrase string
+		if err := r.DecodeData(&passphrase); err != nil {
+			return err
+		}
+		return g.setPassphrase(p, passphrase)
+	case "spectatorpassphrase":
+		var passphrase string
+		if err := r.DecodeData(&passphrase); err != nil {
+			return err
+		}
+		return g.setSpectatorPassphrase(p, passphrase)
+	case "spectatorsdisabled":
+		var disabled bool
+		if err := r.DecodeData(&disabled); err != nil {
+			return err
+		}
+		return g.setSpectatorsDisabled(p, disabled)
+	case "start":
+		return g.startGame(p)
+	case "switchteams":
+		return g.switchTeams(p)
+	case "switchapproval":
+		var required bool
+		if err := r.DecodeData(&required); err != nil {
+			return err
+		}
+		return g.setSwitchApproval(p, required)
+	case "approveswitch", "denyswitch":
+		var name string
+		if err := r.DecodeData(&name); err != nil {
+			return err
+		}
+		return g.answerSwitch(p, name, strings.ToLower(r.Type) == "approveswitch")
+	case "shuffleteams":
+		var method string
+		if err := r.DecodeData(&method); err != nil {
+			return err
+		}
+		return g.shuffleTeams(p, method)
+	case "startdraft":
+		var captains []string
+		if err := r.DecodeData(&captains); err != nil {
+			return err
+		}
+		if len(captains) != 2 {
+			return fail.New("Invalid data for startdraft, wanted a list of two captains")
+		}
+		return g.startDraft(p, captains[0], captains[1])
+	case "draftpick":
+		var name string
+		if err := r.DecodeData(&name); err != nil {
+			return err
+		}
+		return g.draftPick(p, name)
+	case "addname":
+		var name string
+		if err := r.DecodeData(&name); err != nil {
+			return err
+		}
+		return p.addName(name)
+	case "removename":
+		var name string
+		if err := r.DecodeData(&name); err != nil {
+			return err
+		}
+		return p.removeName(name)
+	case "startturn":
+		return g.startTurn(p)
+	case "nextname":
+		return g.nextName(p)
+	case "guess":
+		var guess string
+		if err := r.DecodeData(&guess); err != nil {
+			return err
+		}
+		return g.guess(p, guess)
+	case "clue":
+		var clue string
+		if err := r.DecodeData(&clue); err != nil {
+			return err
+		}
+		return g.clue(p, clue)
+	case "chat":
+		var chat chatRequest
+		if err := r.DecodeData(&chat); err != nil {
+			return err
+		}
+		return g.chat(p, chat.Channel, chat.Text)
+	case "mutechat":
+		var muted bool
+		if err := r.DecodeData(&muted); err != nil {
+			return err
+		}
+		return g.muteChat(p, muted)
+	case "react":
+		var reaction string
+		if err := r.DecodeData(&reaction); err != nil {
+			return err
+		}
+		return g.react(p, reaction)
+	case "stealyes":
+		return g.stealConfirm(p, true)
+	case "stealno":
+		return g.stealConfirm(p, false)
+	case "releaseplayer":
+		var name string
+		if err := r.DecodeData(&name); err != nil {
+			return err
+		}
+		return g.releasePlayer(p, name)
+	case "reset":
+		return g.reset(p, "")
+	case "requestupdate":
+		sendSnapshot(g, p)
+		return nil
+	default:
+		return fail.New("%s is an invalid message type", r.Type)
+	}
+}
 
-	select {
-	case <-time.After(clientTimeout):
-		return false
-	case <-p.chanPing:
+func (p *Player) ok(err error) bool {
+	if err != nil {
+		p.SendMsg(ErrorMsg(err))
 		return true
 	}
+	return false
 }
 
 func (p *Player) isLeader() bool {
-	p.RLock()
-	defer p.RUnlock()
 	return p.Name == p.game.Leader.Name
 }
 
 func (p *Player) names() []nameItem {
-	p.RLock()
-	defer p.RUnlock()
 	names := make([]nameItem, 0, len(p.Name))
 
 	for i := range p.Names {
@@ -154,8 +303,6 @@ func (p *Player) names() []nameItem {
 }
 
 func (p *Player) addName(name string) error {
-	p.Lock()
-	defer p.Unlock()
 
 	if p.game.Stage != stageSetup {
 		return fail.New("You cannot add names at this time")
@@ -166,13 +313,10 @@ func (p *Player) addName(name string) error {
 	}
 
 	p.Names = append(p.Names, name)
-	p.game.updatePlayers()
 	return nil
 }
 
 func (p *Player) removeName(name string) error {
-	p.Lock()
-	defer p.Unlock()
 	if p.game.Stage != stageSetup {
 		return fail.New("You cannot remove names at this time")
 	}
@@ -180,7 +324,6 @@ func (p *Player) removeName(name string) error {
 	for i := range p.Names {
 		if p.Names[i] == name {
 			p.Names = append(p.Names[:i], p.Names[i+1:]...)
-			p.game.updatePlayers()
 			return nil
 		}
 	}
@@ -189,11 +332,27 @@ func (p *Player) removeName(name string) error {
 }
 
 func (p *Player) clearNames() {
-	p.Lock()
-	defer p.Unlock()
 	p.Names = nil
 }
 
+func (p *Player) takeTurn(turn int) {
+	p.Turns++
+	p.lastTurn = turn
+}
+
+func (p *Player) turns() int {
+	return p.Turns
+}
+
+func (p *Player) lastTurnTaken() int {
+	return p.lastTurn
+}
+
+func (p *Player) clearTurns() {
+	p.Turns = 0
+	p.lastTurn = 0
+}
+
 func (p *Player) playSound(sound string) {
 	p.SendMsg(Msg{
 		Type: "playsound",
@@ -208,19 +367,33 @@ func (p *Player) sendNotification(notification string) {
 	})
 }
 
-// SendMsg sends a Msg to a player
+// Done is closed when the player's game is closed, and their requests are no longer being received
+func (p *Player) Done() <-chan struct{} {
+	return p.game.done
+}
+
+// SendMsg queues a Msg to be sent to a player in order, it's held on to until the player acknowledges it
 func (p *Player) SendMsg(msg Msg) {
-	go func() {
-		if p == nil {
-			return
-		}
-		p.Send <- msg
-	}()
+	if p == nil {
+		return
+	}
+	p.outbox.add(msg)
+}
+
+// Messages returns the messages sent to the player after the passed in sequence number, and a channel that is
+// closed when the next message is sent.  Returns false if any of the messages have already been dropped, and
+// the player will need to rejoin to get the full game state again
+func (p *Player) Messages(after uint64) ([]Msg, <-chan struct{}, bool) {
+	return p.outbox.since(after)
+}
+
+// Written records that every message up to the passed in sequence number has been written to the player's
+// connection.  Written messages are held until the player acknowledges them, in case they need to be replayed
+func (p *Player) Written(seq uint64) {
+	p.outbox.wrote(seq)
 }
 
-// MarshalJSON implements the JSON Marshaller interface to allow managing locks when the data is marshalled
+// MarshalJSON implements the JSON Marshaller interface so only the player state is sent to clients
 func (p *Player) MarshalJSON() ([]byte, error) {
-	p.RLock()
-	defer p.RUnlock()
 	return json.Marshal(p.playerState)
 }
@@ -0,0 +1,36 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+// Presence comes from the player's websocket connections.  The server pings each connection, and the connection
+// is dropped once the player stops answering, so a player is connected for as long as they have an open connection.
+
+// Connect records a new connection from the player
+func (p *Player) Connect() {
+	p.Seen()
+	p.game.do(func() {
+		p.connections++
+		p.Connected = true
+		p.LastSeen = p.seen.Load()
+	})
+}
+
+// Disconnect records that one of the player's connections has closed
+func (p *Player) Disconnect() {
+	p.game.do(func() {
+		p.connections--
+		if p.connections <= 0 {
+			p.connections = 0
+			p.Connected = false
+		}
+		p.LastSeen = p.seen.Load()
+	})
+}
+
+// Seen records that the player's connection was just heard from, such as answering a ping.  It's only shared with
+// the rest of the game when the player disconnects, so staying connected doesn't send an update every ping
+func (p *Player) Seen() {
+	p.seen.Store(p.game.clock.Now().UnixMilli())
+}
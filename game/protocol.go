@@ -0,0 +1,162 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+// ProtocolVersion is the newest version of the websocket protocol the server speaks.  Clients send the version
+// they speak in their join or watch message, and the server answers with the version that will be used
+const ProtocolVersion = 1
+
+// minProtocolVersion is the oldest version of the protocol still supported
+const minProtocolVersion = 1
+
+// maxRequestSize is the largest websocket message accepted from a client
+const maxRequestSize = 16 * 1024
+
+// Request is a message received from a client.  Its data is decoded into the type expected by the message type
+// once the type is known
+type Request struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// JoinRequest is the data of the first message sent by a player
+type JoinRequest struct {
+	Version    int    `json:"version"`
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Token      string `json:"token"`      // rejoin token, if reconnecting as an existing player
+	Passphrase string `json:"passphrase"` // required for new players in private games
+	LastSeq    uint64 `json:"lastSeq"`    // last message seen when resuming after a reconnect
+}
+
+// WatchRequest is the data of the first message sent by a viewer
+type WatchRequest struct {
+	Version    int    `json:"version"`
+	Code       string `json:"code"`
+	Role       string `json:"role"`
+	Passphrase string `json:"passphrase"`
+}
+
+// Welcome is sent to the client once a join or watch handshake has succeeded
+type Welcome struct {
+	Version    int   `json:"version"`
+	ServerTime int64 `json:"serverTime"` // unix milliseconds, a first guess at the client's clock offset
+}
+
+// NewWelcome builds the welcome message for the negotiated protocol version
+func NewWelcome(version int) Msg {
+	return Msg{Type: "welcome", Data: Welcome{Version: version, ServerTime: time.Now().UnixMilli()}}
+}
+
+// timeSync answers a client's clock sync request.  The client sends its own time, and from the round trip
+// works out how far its clock is from the server's so it can count down to timer deadlines accurately
+type timeSync struct {
+	Client int64 `json:"client"`
+	Server int64 `json:"server"`
+}
+
+// timeSyncMsg decodes a timesync request and builds the reply
+func timeSyncMsg(r Request) (Msg, error) {
+	var client int64
+	err := r.DecodeData(&client)
+	if err != nil {
+		return Msg{}, err
+	}
+	return Msg{Type: "timesync", Data: timeSync{Client: client, Server: time.Now().UnixMilli()}}, nil
+}
+
+type chatRequest struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+// ReadRequest strictly decodes a single request, rejecting unknown fields and trailing data
+func ReadRequest(r io.Reader) (Request, error) {
+	var req Request
+	b, err := io.ReadAll(io.LimitReader(r, maxRequestSize+1))
+	if err != nil {
+		return req, err
+	}
+	if len(b) > maxRequestSize {
+		return req, fail.NewWithStatus("Message is too large", http.StatusRequestEntityTooLarge)
+	}
+
+	err = strictUnmarshal(b, &req)
+	if err != nil {
+		return req, fail.New("Invalid message: %s", err)
+	}
+
+	if req.Type == "" {
+		return req, fail.New("Message type is required")
+	}
+	return req, nil
+}
+
+// DecodeData strictly decodes the data of a request into the passed in type
+func (r Request) DecodeData(result interface{}) error {
+	if len(r.Data) == 0 {
+		return fail.New("Invalid data for %s, data is required", r.Type)
+	}
+
+	err := strictUnmarshal(r.Data, result)
+	if err != nil {
+		return fail.New("Invalid data for %s: %s", r.Type, err)
+	}
+	return nil
+}
+
+func strictUnmarshal(data []byte, result interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(result)
+	if err != nil {
+		return err
+	}
+
+	if dec.More() {
+		return errors.New("unexpected data after the message")
+	}
+	return nil
+}
+
+// NegotiateVersion picks the protocol version to use with a client
+func NegotiateVersion(version int) (int, error) {
+	if version < minProtocolVersion {
+		return 0, fail.NewWithStatus("Your game client is out of date, please refresh the page", http.StatusUpgradeRequired)
+	}
+	if version > ProtocolVersion {
+		return ProtocolVersion, nil
+	}
+	return version, nil
+}
+
+// ErrorMsg builds the error message sent to clients.  Failures are sent as is, any other errors are logged and
+// replaced with a generic failure so internal details aren't exposed
+func ErrorMsg(err error) Msg {
+	var failure *fail.Failure
+	if !errors.As(err, &failure) {
+		log.Printf("Internal Error: %s", err)
+		failure = fail.NewWithStatus("An internal error has occured, please start a new game",
+			http.StatusInternalServerError)
+	}
+
+	return Msg{
+		Type: "error",
+		Data: failure,
+	}
+}
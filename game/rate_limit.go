@@ -82,6 +82,25 @@ func (rd *RateDelay) Attempt(id string) (RateLeft, error) {
 	return left, nil
 }
 
+// Refund gives back an attempt that shouldn't count against the limit
+func (rd *RateDelay) Refund(id string) {
+	key := rateKey{id: id, rateType: rd.Type}
+	key.refund()
+}
+
+func (rk rateKey) refund() {
+	rates.Lock()
+	defer rates.Unlock()
+
+	left, ok := rates.left[rk]
+	if !ok || left.Reset.Before(time.Now()) || left.Remaining >= left.Limit {
+		return
+	}
+
+	left.Remaining++
+	rates.left[rk] = left
+}
+
 func (rk rateKey) attempt(limit int32, reset time.Time) RateLeft {
 	rates.Lock()
 	defer rates.Unlock()
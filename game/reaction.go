@@ -0,0 +1,88 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"time"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+const (
+	reactionLaugh    = "laugh"
+	reactionFacepalm = "facepalm"
+	reactionApplause = "applause"
+)
+
+var reactions = []string{reactionLaugh, reactionFacepalm, reactionApplause}
+
+var reactionRate = &RateLimit{
+	Type:   "reaction",
+	Limit:  5,
+	Period: 5 * time.Second,
+}
+
+type reactionMsg struct {
+	Player   string `json:"player"`
+	Reaction string `json:"reaction"`
+	Earner   string `json:"earner,omitempty"` // the clue giver who earned the reaction
+}
+
+// reactionAward is who earned the most of a given reaction while giving clues
+type reactionAward struct {
+	Reaction string `json:"reaction"`
+	Player   string `json:"player"`
+	Count    int    `json:"count"`
+}
+
+func (g *Game) react(p *Player, reaction string) error {
+	valid := false
+	for i := range reactions {
+		if reactions[i] == reaction {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fail.New("%s is an invalid reaction", reaction)
+	}
+
+	if _, err := reactionRate.Attempt(g.Code + "/" + p.Name); err != nil {
+		// quietly drop reactions sent too quickly
+		return nil
+	}
+
+	msg := reactionMsg{Player: p.Name, Reaction: reaction}
+
+	if (g.Stage == stagePlaying || g.Stage == stageStealing) && g.ClueGiver != nil && g.ClueGiver.Name != p.Name {
+		msg.Earner = g.ClueGiver.Name
+		if g.Stats.reactions[reaction] == nil {
+			g.Stats.reactions[reaction] = make(map[string]int)
+		}
+		g.Stats.reactions[reaction][msg.Earner]++
+	}
+
+	g.Team1.sendMsg(Msg{Type: "reaction", Data: msg})
+	g.Team2.sendMsg(Msg{Type: "reaction", Data: msg})
+	return nil
+}
+
+// reactionAwards finds who earned the most of each reaction
+func reactionAwards(g *Game) []reactionAward {
+	var awards []reactionAward
+	for _, reaction := range reactions {
+		award := reactionAward{Reaction: reaction}
+		for player, count := range g.Stats.reactions[reaction] {
+			if count > award.Count || (count == award.Count && player < award.Player) {
+				award.Player = player
+				award.Count = count
+			}
+		}
+		if award.Count > 0 {
+			awards = append(awards, award)
+		}
+	}
+	return awards
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+// rejoin tokens are secrets handed to a player when they first join, and are required to reconnect as that
+// player, so no one else can take over their seat by using the same name
+
+func newToken() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic("Error generating rejoin token: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// rejoin checks whether an existing player can be reconnected with the given token
+func rejoin(p *Player, token string) error {
+	current := p.token
+
+	if current != "" && subtle.ConstantTimeCompare([]byte(current), []byte(token)) != 1 {
+		return fail.New("A player with the name %s is already in this game, please choose a new name or ask "+
+			"the game leader to release them", p.Name)
+	}
+
+	if current == "" {
+		// player was released by the leader, so whoever takes their seat gets a new token
+		if p.Connected {
+			return fail.New("A player with the name %s is already connected, please choose a new name", p.Name)
+		}
+		issueToken(p)
+		return nil
+	}
+
+	sendToken(p)
+	return nil
+}
+
+// resumeFrom is where a reconnecting player's messages pick back up.  If everything after the last message they saw
+// is still held it's replayed, otherwise they start fresh with the snapshot sent when they rejoin
+func resumeFrom(p *Player, lastSeq uint64) uint64 {
+	if lastSeq > 0 {
+		if _, _, ok := p.outbox.since(lastSeq); ok {
+			return lastSeq
+		}
+	}
+	return p.outbox.last()
+}
+
+func issueToken(p *Player) {
+	p.token = newToken()
+	sendToken(p)
+}
+
+func sendToken(p *Player) {
+	p.SendMsg(Msg{Type: "token", Data: p.token})
+}
+
+// releasePlayer lets a leader clear a player's rejoin token so they can reconnect from a new device
+func (g *Game) releasePlayer(who *Player, name string) error {
+	if !who.isLeader() {
+		return fail.New("Only game leaders can release players")
+	}
+
+	p, ok := findPlayer(g, name)
+	if !ok {
+		return fail.New("%s is not in this game", name)
+	}
+
+	if p == who {
+		return fail.New("You can't release yourself")
+	}
+
+	p.token = ""
+
+	log.Printf("Player %s released in game %s", name, g.Code)
+	who.sendNotification(name + " can now rejoin from a new device")
+	return nil
+}
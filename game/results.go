@@ -0,0 +1,188 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResultsRetention is how long the results of a finished game can still be retrieved after the game
+// itself has been cleaned up
+var ResultsRetention = 24 * time.Hour
+
+// Results are the final results of a finished game
+type Results struct {
+	Code       string         `json:"code"`
+	Finished   time.Time      `json:"finished"`
+	Winner     int            `json:"winner"`
+	Team1Score int            `json:"team1Score"`
+	Team2Score int            `json:"team2Score"`
+	Team1      []string       `json:"team1"`
+	Team2      []string       `json:"team2"`
+	Players    []PlayerResult `json:"players"`
+	Names      []NameResult   `json:"names"`
+}
+
+// PlayerResult is a single player's stats from a finished game
+type PlayerResult struct {
+	Name           string `json:"name"`
+	Team           int    `json:"team"`
+	Guesses        int    `json:"guesses"`        // names guessed while they were giving clues
+	Stolen         int    `json:"stolen"`         // names stolen while they were giving clues
+	NamesSubmitted int    `json:"namesSubmitted"` // names they put in the hat
+}
+
+// NameResult records a single name being guessed
+type NameResult struct {
+	Name      string  `json:"name"`
+	Submitter string  `json:"submitter"`
+	Round     int     `json:"round"`
+	ClueGiver string  `json:"clueGiver"`
+	Team      int     `json:"team"` // which team scored the name
+	Stolen    bool    `json:"stolen"`
+	GuessTime float64 `json:"guessTime"` // in seconds
+	Points    int     `json:"points"`
+}
+
+type archivedResults struct {
+	results *Results
+	expires time.Time // zero while the game is still active
+}
+
+var results = struct {
+	sync.RWMutex
+	games map[string]*archivedResults
+}{games: make(map[string]*archivedResults)}
+
+// FindResults finds the results of the most recently finished game for the given code
+func FindResults(code string) (*Results, bool) {
+	results.RLock()
+	defer results.RUnlock()
+
+	archived, ok := results.games[strings.ToUpper(code)]
+	if !ok {
+		return nil, false
+	}
+
+	if !archived.expires.IsZero() && archived.expires.Before(time.Now()) {
+		return nil, false
+	}
+	return archived.results, true
+}
+
+// newResults builds the results of the game
+func newResults(g *Game) *Results {
+	r := &Results{
+		Code:       g.Code,
+		Finished:   g.clock.Now(),
+		Winner:     g.Stats.Winner,
+		Team1Score: g.Stats.Team1Score,
+		Team2Score: g.Stats.Team2Score,
+		Names:      make([]NameResult, len(g.history)),
+	}
+
+	copy(r.Names, g.history)
+
+	addPlayers := func(team *Team, teamNum int) []string {
+		var roster []string
+		for _, p := range team.Players {
+			roster = append(roster, p.Name)
+			r.Players = append(r.Players, PlayerResult{
+				Name:           p.Name,
+				Team:           teamNum,
+				Guesses:        g.Stats.BestClueGiver.stats[p.Name],
+				Stolen:         g.Stats.MostStolen.stats[p.Name],
+				NamesSubmitted: len(p.names()),
+			})
+		}
+		return roster
+	}
+
+	r.Team1 = addPlayers(&g.Team1, 1)
+	r.Team2 = addPlayers(&g.Team2, 2)
+
+	sort.SliceStable(r.Players, func(i, j int) bool {
+		return r.Players[i].Guesses > r.Players[j].Guesses
+	})
+
+	return r
+}
+
+func archiveResults(r *Results) {
+	results.Lock()
+	defer results.Unlock()
+
+	results.games[r.Code] = &archivedResults{results: r}
+}
+
+// expireResults starts the retention period on a game's results once the game has been removed
+func expireResults(code string) {
+	results.Lock()
+	defer results.Unlock()
+
+	archived, ok := results.games[code]
+	if !ok {
+		return
+	}
+
+	archived.expires = time.Now().Add(ResultsRetention)
+	time.AfterFunc(ResultsRetention, func() {
+		results.Lock()
+		defer results.Unlock()
+		if current, ok := results.games[code]; ok && current == archived {
+			delete(results.games, code)
+		}
+	})
+}
+
+func resultsArchived(code string) bool {
+	results.RLock()
+	defer results.RUnlock()
+	_, ok := results.games[code]
+	return ok
+}
+
+// WriteCSV writes the results as CSV, with a section each for the game, the players and the names guessed
+func (r *Results) WriteCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	itoa := strconv.Itoa
+
+	records := [][]string{
+		{"Game", "Finished", "Winner", "Team 1 Score", "Team 2 Score"},
+		{r.Code, r.Finished.UTC().Format(time.RFC3339), itoa(r.Winner), itoa(r.Team1Score), itoa(r.Team2Score)},
+		{},
+		{"Player", "Team", "Guesses", "Stolen", "Names Submitted"},
+	}
+
+	for _, p := range r.Players {
+		records = append(records, []string{csvText(p.Name), itoa(p.Team), itoa(p.Guesses), itoa(p.Stolen),
+			itoa(p.NamesSubmitted)})
+	}
+
+	records = append(records, []string{},
+		[]string{"Name", "Submitter", "Round", "Clue Giver", "Team", "Stolen", "Guess Time", "Points"})
+
+	for _, n := range r.Names {
+		records = append(records, []string{csvText(n.Name), csvText(n.Submitter), itoa(n.Round),
+			csvText(n.ClueGiver), itoa(n.Team), strconv.FormatBool(n.Stolen),
+			strconv.FormatFloat(n.GuessTime, 'f', 1, 64), itoa(n.Points)})
+	}
+
+	return c.WriteAll(records)
+}
+
+// csvText escapes text entered by players, so spreadsheets don't run anything that looks like a formula
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
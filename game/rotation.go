@@ -0,0 +1,136 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"sort"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+// how the next clue giver is picked from a team
+const (
+	rotationRoundRobin = "roundrobin"  // in team order
+	rotationRandom     = "random"      // randomly, but everyone goes once before anyone goes again
+	rotationFewest     = "fewestturns" // whoever has had the fewest turns, and longest ago
+)
+
+func (g *Game) setRotation(who *Player, rotation string) error {
+	if g.Stage != stagePregame {
+		return fail.New("The clue giver rotation cannot be changed after the game has started")
+	}
+
+	if !who.isLeader() {
+		return fail.New("Only game leaders can change the clue giver rotation")
+	}
+
+	switch rotation {
+	case rotationRoundRobin, rotationRandom, rotationFewest:
+		g.Rotation = rotation
+	default:
+		return fail.New("%s is an invalid clue giver rotation", rotation)
+	}
+	return nil
+}
+
+// setRotationOrder reorders the players on each team to match the order of the passed in names, which sets
+// the order clue givers take their turns in.  Players not in the list keep their order after those that are.
+func (g *Game) setRotationOrder(who *Player, order []string) error {
+	if g.Stage != stagePregame {
+		return fail.New("The clue giver order cannot be changed after the game has started")
+	}
+
+	if !who.isLeader() {
+		return fail.New("Only game leaders can change the clue giver order")
+	}
+
+	position := make(map[string]int, len(order))
+	for i := range order {
+		position[order[i]] = i
+	}
+
+	sortTeam := func(team *Team) {
+		sort.SliceStable(team.Players, func(i, j int) bool {
+			pi, iOk := position[team.Players[i].Name]
+			pj, jOk := position[team.Players[j].Name]
+			if iOk && jOk {
+				return pi < pj
+			}
+			return iOk && !jOk
+		})
+	}
+
+	sortTeam(&g.Team1)
+	sortTeam(&g.Team2)
+	return nil
+}
+
+// nextClueGiver picks the next clue giver from the team based on the game's rotation
+func nextClueGiver(g *Game, team *Team, index *int, bag *[]string) *Player {
+	switch g.Rotation {
+	case rotationRandom:
+		return randomClueGiver(g, team, bag)
+	case rotationFewest:
+		return fewestTurnsClueGiver(team)
+	default:
+		*index++
+		if *index >= len(team.Players) {
+			*index = 0
+		}
+		return team.Players[*index]
+	}
+}
+
+// randomClueGiver draws clue givers out of a bag, so everyone gets a turn before the bag is refilled
+func randomClueGiver(g *Game, team *Team, bag *[]string) *Player {
+	for {
+		if len(*bag) == 0 {
+			*bag = refillBag(team)
+		}
+
+		i := g.rand.Intn(len(*bag))
+		name := (*bag)[i]
+		*bag = append((*bag)[:i], (*bag)[i+1:]...)
+
+		// players may have left or switched teams since the bag was filled
+		if p, ok := team.player(name); ok {
+			return p
+		}
+	}
+}
+
+func refillBag(team *Team) []string {
+	bag := make([]string, 0, len(team.Players))
+	last := lastTeamTurn(team)
+	for _, p := range team.Players {
+		// don't let whoever went last on this team go twice in a row when the bag is refilled
+		if len(team.Players) > 1 && last > 0 && p.lastTurnTaken() == last {
+			continue
+		}
+		bag = append(bag, p.Name)
+	}
+	return bag
+}
+
+func lastTeamTurn(team *Team) int {
+	last := 0
+	for _, p := range team.Players {
+		if turn := p.lastTurnTaken(); turn > last {
+			last = turn
+		}
+	}
+	return last
+}
+
+func fewestTurnsClueGiver(team *Team) *Player {
+	next := team.Players[0]
+	for _, p := range team.Players[1:] {
+		turns, nextTurns := p.turns(), next.turns()
+		if turns < nextTurns || (turns == nextTurns && p.lastTurnTaken() < next.lastTurnTaken()) {
+			next = p
+		}
+	}
+	return next
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"time"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+const maxPoints = 10
+
+// scoring sets how many points each name is worth
+type scoring struct {
+	RoundPoints  []int `json:"roundPoints"`  // points for a name guessed in each round
+	StealPoints  int   `json:"stealPoints"`  // points for stealing a name
+	SpeedBonus   int   `json:"speedBonus"`   // extra points for a quickly guessed name, 0 for no bonus
+	SpeedSeconds int   `json:"speedSeconds"` // how quickly a name needs to be guessed to earn the speed bonus
+}
+
+// scoreBreakdown is where a team's points came from
+type scoreBreakdown struct {
+	Guesses     int   `json:"guesses"`     // names guessed on their own turns
+	GuessPoints int   `json:"guessPoints"` // points from names guessed on their own turns, without bonuses
+	Steals      int   `json:"steals"`      // names stolen from the other team
+	StealPoints int   `json:"stealPoints"`
+	SpeedBonus  int   `json:"speedBonus"`
+	Rounds      []int `json:"rounds"` // total points earned in each round
+}
+
+func defaultScoring() scoring {
+	return scoring{
+		RoundPoints:  []int{1, 1, 1},
+		StealPoints:  1,
+		SpeedSeconds: 5,
+	}
+}
+
+func (g *Game) setScoring(who *Player, s scoring) error {
+	if g.Stage != stagePregame {
+		return fail.New("Scoring cannot be changed after the game has started")
+	}
+
+	if !who.isLeader() {
+		return fail.New("Only game leaders can change the scoring")
+	}
+
+	if len(s.RoundPoints) != 3 {
+		return fail.New("Points must be set for each of the 3 rounds")
+	}
+
+	for _, points := range append(s.RoundPoints, s.StealPoints, s.SpeedBonus) {
+		if points < 0 || points > maxPoints {
+			return fail.New("Points must be between 0 and %d", maxPoints)
+		}
+	}
+
+	if s.SpeedSeconds <= 0 || s.SpeedSeconds >= secondsPerTurn {
+		return fail.New("A speed bonus must be earned in between 1 and %d seconds", secondsPerTurn-1)
+	}
+
+	g.Scoring = s
+	return nil
+}
+
+// score awards the points for the current name
+func score(g *Game, team1, steal bool, guessTime time.Duration) {
+	breakdown := &g.Stats.Team2Breakdown
+	total := &g.Stats.Team2Score
+	if team1 {
+		breakdown = &g.Stats.Team1Breakdown
+		total = &g.Stats.Team1Score
+	}
+
+	points := 0
+	if steal {
+		points = g.Scoring.StealPoints
+		breakdown.Steals++
+		breakdown.StealPoints += points
+	} else {
+		points = g.Scoring.RoundPoints[g.Round-1]
+		breakdown.Guesses++
+		breakdown.GuessPoints += points
+		if g.Scoring.SpeedBonus > 0 && guessTime <= time.Duration(g.Scoring.SpeedSeconds)*time.Second {
+			points += g.Scoring.SpeedBonus
+			breakdown.SpeedBonus += g.Scoring.SpeedBonus
+		}
+	}
+
+	breakdown.Rounds[g.Round-1] += points
+	*total += points
+
+	if len(g.history) > 0 {
+		g.history[len(g.history)-1].Points = points
+	}
+}
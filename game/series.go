@@ -0,0 +1,176 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"log"
+	"sort"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+const maxSeriesGames = 9
+
+// series tracks the cumulative scores of a best of N series of games played under the same game code
+type series struct {
+	Games      int            `json:"games"`   // best of N, 1 means no series is being played
+	Shuffle    bool           `json:"shuffle"` // shuffle the teams between each game in the series
+	Played     []seriesGame   `json:"played"`
+	Team1Wins  int            `json:"team1Wins"`
+	Team2Wins  int            `json:"team2Wins"`
+	Team1Score int            `json:"team1Score"`
+	Team2Score int            `json:"team2Score"`
+	Players    []seriesPlayer `json:"players"` // cumulative scoreboard, sorted by wins then points
+	Over       bool           `json:"over"`
+	Winners    []string       `json:"winners"` // players who won the series, set once the series is over
+}
+
+type seriesGame struct {
+	Winner     int      `json:"winner"`
+	Team1Score int      `json:"team1Score"`
+	Team2Score int      `json:"team2Score"`
+	Team1      []string `json:"team1"`
+	Team2      []string `json:"team2"`
+}
+
+type seriesPlayer struct {
+	Name   string `json:"name"`
+	Wins   int    `json:"wins"`
+	Points int    `json:"points"` // total points scored by their team in the games they played
+}
+
+func (g *Game) setSeriesGames(who *Player, games int) error {
+	err := canChangeSeries(g, who)
+	if err != nil {
+		return err
+	}
+
+	if games <= 0 || games%2 == 0 {
+		return fail.New("A series must be best of an odd number of games")
+	}
+
+	if games > maxSeriesGames {
+		return fail.New("The longest series is best of %d", maxSeriesGames)
+	}
+
+	if len(g.Series.Played) > 0 && !g.Series.Over {
+		return fail.New("The length of the series cannot be changed once it has started")
+	}
+
+	g.Series.Games = games
+	return nil
+}
+
+func (g *Game) setSeriesShuffle(who *Player, shuffle bool) error {
+	err := canChangeSeries(g, who)
+	if err != nil {
+		return err
+	}
+
+	g.Series.Shuffle = shuffle
+	return nil
+}
+
+func canChangeSeries(g *Game, who *Player) error {
+	if g.Stage != stagePregame {
+		return fail.New("The series cannot be changed after the game has started")
+	}
+
+	if !who.isLeader() {
+		return fail.New("Only game leaders can change the series")
+	}
+	return nil
+}
+
+func (s *series) active() bool {
+	return s.Games > 1
+}
+
+// recordSeriesGame adds the just finished game to the series
+func recordSeriesGame(g *Game) {
+	s := &g.Series
+	if !s.active() || s.Over {
+		return
+	}
+
+	played := seriesGame{
+		Winner:     g.Stats.Winner,
+		Team1Score: g.Stats.Team1Score,
+		Team2Score: g.Stats.Team2Score,
+	}
+
+	switch g.Stats.Winner {
+	case 1:
+		s.Team1Wins++
+	case 2:
+		s.Team2Wins++
+	}
+	s.Team1Score += g.Stats.Team1Score
+	s.Team2Score += g.Stats.Team2Score
+
+	for _, p := range g.Team1.Players {
+		played.Team1 = append(played.Team1, p.Name)
+		s.player(p.Name).add(g.Stats.Winner == 1, g.Stats.Team1Score)
+	}
+	for _, p := range g.Team2.Players {
+		played.Team2 = append(played.Team2, p.Name)
+		s.player(p.Name).add(g.Stats.Winner == 2, g.Stats.Team2Score)
+	}
+
+	s.Played = append(s.Played, played)
+
+	sort.SliceStable(s.Players, func(i, j int) bool {
+		if s.Players[i].Wins == s.Players[j].Wins {
+			return s.Players[i].Points > s.Players[j].Points
+		}
+		return s.Players[i].Wins > s.Players[j].Wins
+	})
+
+	// with fixed teams, a series is over as soon as one team can't be caught
+	majority := s.Games/2 + 1
+	if len(s.Played) < s.Games && (s.Shuffle || (s.Team1Wins < majority && s.Team2Wins < majority)) {
+		return
+	}
+
+	s.Over = true
+	for _, p := range s.Players {
+		if p.Wins != s.Players[0].Wins || p.Points != s.Players[0].Points {
+			break
+		}
+		s.Winners = append(s.Winners, p.Name)
+	}
+	log.Printf("Series in game %s finished after %d games", g.Code, len(s.Played))
+}
+
+func (s *series) player(name string) *seriesPlayer {
+	for i := range s.Players {
+		if s.Players[i].Name == name {
+			return &s.Players[i]
+		}
+	}
+	s.Players = append(s.Players, seriesPlayer{Name: name})
+	return &s.Players[len(s.Players)-1]
+}
+
+func (sp *seriesPlayer) add(won bool, points int) {
+	if won {
+		sp.Wins++
+	}
+	sp.Points += points
+}
+
+// nextSeriesGame prepares the series for the next game after a reset
+func nextSeriesGame(g *Game) {
+	s := &g.Series
+	if s.Over {
+		// start a new series with the same settings
+		*s = series{Games: s.Games, Shuffle: s.Shuffle}
+		return
+	}
+
+	if s.active() && s.Shuffle && len(s.Played) > 0 {
+		shuffleTeams(g)
+	}
+}
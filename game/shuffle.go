@@ -0,0 +1,98 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"sort"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+const (
+	shuffleRandom  = "random"  // randomly split players across teams
+	shuffleBalance = "balance" // spread the best clue givers from the previous game across teams
+)
+
+func (g *Game) shuffleTeams(who *Player, method string) error {
+	if g.Stage != stagePregame {
+		return fail.New("Teams cannot be shuffled after the game has started")
+	}
+
+	if !who.isLeader() {
+		return fail.New("Only game leaders can shuffle the teams")
+	}
+
+	if g.Draft.Active {
+		return fail.New("Teams cannot be shuffled during a draft")
+	}
+
+	switch method {
+	case shuffleRandom:
+		shuffleTeams(g)
+	case shuffleBalance:
+		if g.lastResults == nil {
+			return fail.New("Teams can only be balanced after a game has been played")
+		}
+		balanceTeams(g)
+	default:
+		return fail.New("%s is an invalid way to shuffle the teams", method)
+	}
+
+	return nil
+}
+
+// shuffleTeams randomly redistributes all players evenly across both teams
+func shuffleTeams(g *Game) {
+	players := shuffledPlayers(g)
+
+	g.Team1.Players = nil
+	g.Team2.Players = nil
+	for i := range players {
+		if i%2 == 0 {
+			g.Team1.addExistingPlayer(players[i])
+		} else {
+			g.Team2.addExistingPlayer(players[i])
+		}
+	}
+}
+
+// balanceTeams redistributes the players so that the clue givers who earned the most guesses in the previous game
+// are spread evenly across both teams.  Players who didn't play in the previous game are placed randomly.
+func balanceTeams(g *Game) {
+	guesses := make(map[string]int)
+	for _, p := range g.lastResults.Players {
+		guesses[p.Name] = p.Guesses
+	}
+
+	// shuffle first so that players with the same number of guesses don't always end up on the same team
+	players := shuffledPlayers(g)
+	sort.SliceStable(players, func(i, j int) bool {
+		return guesses[players[i].Name] > guesses[players[j].Name]
+	})
+
+	maxSize := (len(players) + 1) / 2
+	team1Total, team2Total := 0, 0
+
+	g.Team1.Players = nil
+	g.Team2.Players = nil
+	for _, p := range players {
+		if len(g.Team2.Players) >= maxSize ||
+			(len(g.Team1.Players) < maxSize && team1Total <= team2Total) {
+			g.Team1.addExistingPlayer(p)
+			team1Total += guesses[p.Name]
+			continue
+		}
+		g.Team2.addExistingPlayer(p)
+		team2Total += guesses[p.Name]
+	}
+}
+
+func shuffledPlayers(g *Game) []*Player {
+	players := append(g.Team1.copyPlayers(), g.Team2.copyPlayers()...)
+	g.rand.Shuffle(len(players), func(i, j int) {
+		players[i], players[j] = players[j], players[i]
+	})
+	return players
+}
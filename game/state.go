@@ -0,0 +1,90 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strconv"
+)
+
+// stateTracker keeps the last state sent to a group of clients, so that only the top level fields that changed
+// need to be sent on each update.  Every change bumps the version, and clients that miss a version ask for a
+// full snapshot
+type stateTracker struct {
+	version int
+	fields  map[string]json.RawMessage
+}
+
+// statePatch is the set of top level state fields that changed from the previous version
+type statePatch struct {
+	Version int                        `json:"version"`
+	Fields  map[string]json.RawMessage `json:"fields"`
+	Removed []string                   `json:"removed,omitempty"`
+}
+
+// patch returns a patch message with the changes since the last update, or false if nothing has changed
+func (t *stateTracker) patch(state interface{}) (Msg, bool) {
+	p, ok := t.diff(state)
+	if !ok {
+		return Msg{}, false
+	}
+	return Msg{Type: "patch", Data: p}, true
+}
+
+// snapshot returns a message with the full state.  Any changes that haven't been sent yet are included in the
+// snapshot's version, and are returned as a patch so they can be sent to everyone else
+func (t *stateTracker) snapshot(state interface{}) (snapshot Msg, patch Msg, changed bool) {
+	p, changed := t.diff(state)
+	if changed {
+		patch = Msg{Type: "patch", Data: p}
+	}
+
+	full := make(map[string]json.RawMessage, len(t.fields)+1)
+	for k, v := range t.fields {
+		full[k] = v
+	}
+	full["version"] = json.RawMessage(strconv.Itoa(t.version))
+
+	return Msg{Type: "state", Data: full}, patch, changed
+}
+
+// diff compares the state to the last one sent
+func (t *stateTracker) diff(state interface{}) (*statePatch, bool) {
+	b, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("Error marshalling game state: %s", err)
+		return nil, false
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		log.Printf("Error splitting game state into fields: %s", err)
+		return nil, false
+	}
+
+	p := &statePatch{Fields: make(map[string]json.RawMessage)}
+	for k, v := range fields {
+		if old, ok := t.fields[k]; !ok || !bytes.Equal(old, v) {
+			p.Fields[k] = v
+		}
+	}
+	for k := range t.fields {
+		if _, ok := fields[k]; !ok {
+			p.Removed = append(p.Removed, k)
+		}
+	}
+
+	if len(p.Fields) == 0 && len(p.Removed) == 0 {
+		return nil, false
+	}
+
+	t.version++
+	t.fields = fields
+	p.Version = t.version
+	return p, true
+}
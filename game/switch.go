@@ -0,0 +1,107 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+func (g *Game) switchTeams(who *Player) error {
+	err := canSwitchTeams(g, who)
+	if err != nil {
+		return err
+	}
+
+	if g.SwitchApproval && !who.isLeader() {
+		for _, name := range g.SwitchRequests {
+			if name == who.Name {
+				return nil
+			}
+		}
+		g.SwitchRequests = append(g.SwitchRequests, who.Name)
+		g.Leader.sendNotification(who.Name + " has asked to switch teams")
+		return nil
+	}
+
+	switchTeams(g, who)
+	return nil
+}
+
+func (g *Game) setSwitchApproval(who *Player, required bool) error {
+	if !who.isLeader() {
+		return fail.New("Only game leaders can change whether switching teams needs approval")
+	}
+
+	g.SwitchApproval = required
+	if !required {
+		g.SwitchRequests = nil
+	}
+	return nil
+}
+
+// answerSwitch approves or denies a player's pending request to switch teams
+func (g *Game) answerSwitch(who *Player, name string, approved bool) error {
+	if !who.isLeader() {
+		return fail.New("Only game leaders can approve switching teams")
+	}
+
+	found := false
+	for i := range g.SwitchRequests {
+		if g.SwitchRequests[i] == name {
+			g.SwitchRequests = append(g.SwitchRequests[:i], g.SwitchRequests[i+1:]...)
+			found = true
+			break
+		}
+	}
+
+	if !found || !approved {
+		return nil
+	}
+
+	p, ok := findPlayer(g, name)
+	if !ok {
+		return nil
+	}
+
+	err := canSwitchTeams(g, p)
+	if err != nil {
+		return err
+	}
+
+	switchTeams(g, p)
+	return nil
+}
+
+// canSwitchTeams only allows switching teams before any turns have been taken, otherwise a player could score
+// for both teams.  Once the game has started, a switch can't leave the player's team without enough players to play
+func canSwitchTeams(g *Game, who *Player) error {
+	if g.Stage != stagePregame && g.Stage != stageSetup {
+		return fail.New("Teams cannot be switched once the game has started")
+	}
+
+	if g.Draft.Active {
+		return fail.New("Teams cannot be switched during a draft")
+	}
+
+	if g.Stage == stageSetup {
+		team := &g.Team1
+		if _, ok := g.Team2.player(who.Name); ok {
+			team = &g.Team2
+		}
+		if len(team.Players) <= minTeamPlayers {
+			return fail.New("Each team needs at least %d players", minTeamPlayers)
+		}
+	}
+	return nil
+}
+
+func switchTeams(g *Game, who *Player) {
+	if g.Team1.removePlayer(who.Name) {
+		g.Team2.addExistingPlayer(who)
+	} else {
+		g.Team2.removePlayer(who.Name)
+		g.Team1.addExistingPlayer(who)
+	}
+}
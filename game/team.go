@@ -4,6 +4,10 @@
 
 package game
 
+import (
+	"time"
+)
+
 // Team is a group of players
 type Team struct {
 	// Name    string    `json:"name"`
@@ -39,15 +43,10 @@ func (t *Team) removePlayer(name string) bool {
 	return false
 }
 
-func (t *Team) updatePlayers(state gameState) {
+// isDead is whether every player on the team has been gone for longer than the passed in duration
+func (t *Team) isDead(now time.Time, gone time.Duration) bool {
 	for i := range t.Players {
-		t.Players[i].update(state)
-	}
-}
-
-func (t *Team) isDead() bool {
-	for i := range t.Players {
-		if t.Players[i].ping() {
+		if t.Players[i].Connected || now.Sub(time.UnixMilli(t.Players[i].LastSeen)) < gone {
 			return false
 		}
 	}
@@ -67,7 +66,7 @@ func (t *Team) copyPlayers() []*Player {
 func (t *Team) cleanPlayers() {
 	var remove []string
 	for i := range t.Players {
-		if !t.Players[i].ping() {
+		if !t.Players[i].Connected {
 			remove = append(remove, t.Players[i].Name)
 		}
 	}
@@ -83,12 +82,24 @@ func (t *Team) clearNames() {
 	}
 }
 
+func (t *Team) clearTurns() {
+	for _, p := range t.Players {
+		p.clearTurns()
+	}
+}
+
 func (t *Team) playSound(sound string) {
 	for _, p := range t.Players {
 		p.playSound(sound)
 	}
 }
 
+func (t *Team) sendMsg(msg Msg) {
+	for _, p := range t.Players {
+		p.SendMsg(msg)
+	}
+}
+
 func (t *Team) sendNotification(notification string) {
 	for _, p := range t.Players {
 		p.sendNotification(notification)
@@ -0,0 +1,176 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+// whether a team's time bank is refilled every round, or has to last the whole game
+const (
+	timeBankRound = "round"
+	timeBankGame  = "game"
+)
+
+const maxTimeBankSeconds = 600
+
+// timeBank gives each team a total amount of time to spend across all of their turns. Each turn's time is
+// deducted from the team's bank, and a team with an empty bank is skipped until the round ends.
+type timeBank struct {
+	Seconds   int    `json:"seconds"` // size of each team's bank, 0 when the time bank isn't being used
+	Scope     string `json:"scope"`
+	Team1Left int    `json:"team1Left"`
+	Team2Left int    `json:"team2Left"`
+
+	team1     time.Duration
+	team2     time.Duration
+	turnStart time.Time // zero when a turn isn't being timed
+}
+
+func (g *Game) setTimeBank(who *Player, seconds int) error {
+	err := canChangeTimeBank(g, who)
+	if err != nil {
+		return err
+	}
+
+	if seconds < 0 {
+		return fail.New("The time bank cannot be negative")
+	}
+
+	if seconds > maxTimeBankSeconds {
+		return fail.New("The maximum time bank is %d seconds", maxTimeBankSeconds)
+	}
+
+	if seconds > 0 && seconds < secondsPerTurn {
+		return fail.New("The time bank must be at least %d seconds", secondsPerTurn)
+	}
+
+	g.TimeBank.Seconds = seconds
+	return nil
+}
+
+func (g *Game) setTimeBankScope(who *Player, scope string) error {
+	err := canChangeTimeBank(g, who)
+	if err != nil {
+		return err
+	}
+
+	if scope != timeBankRound && scope != timeBankGame {
+		return fail.New("%s is an invalid time bank scope", scope)
+	}
+
+	g.TimeBank.Scope = scope
+	return nil
+}
+
+func canChangeTimeBank(g *Game, who *Player) error {
+	if g.Stage != stagePregame {
+		return fail.New("The time bank cannot be changed after the game has started")
+	}
+
+	if !who.isLeader() {
+		return fail.New("Only game leaders can change the time bank")
+	}
+	return nil
+}
+
+func (tb *timeBank) enabled() bool {
+	return tb.Seconds > 0
+}
+
+// fillTimeBank refills each team's bank at the start of a round if needed
+func fillTimeBank(g *Game, round int) {
+	tb := &g.TimeBank
+	if !tb.enabled() || (tb.Scope == timeBankGame && round != 1) {
+		return
+	}
+
+	tb.team1 = time.Duration(tb.Seconds) * time.Second
+	tb.team2 = tb.team1
+	tb.turnStart = time.Time{}
+	updateTimeBankLeft(g)
+}
+
+// turnSeconds is how long the current clue giver's turn is
+func turnSeconds(g *Game) int {
+	if !g.TimeBank.enabled() {
+		return secondsPerTurn
+	}
+
+	left := g.TimeBank.team2
+	if g.clueGiverTrack.team1 {
+		left = g.TimeBank.team1
+	}
+
+	seconds := int(math.Ceil(left.Seconds()))
+	if seconds > secondsPerTurn {
+		return secondsPerTurn
+	}
+	return seconds
+}
+
+func startTurnClock(g *Game) {
+	if g.TimeBank.enabled() {
+		g.TimeBank.turnStart = g.clock.Now()
+	}
+}
+
+// stopTurnClock deducts the time the current turn took from the clue giving team's bank
+func stopTurnClock(g *Game) {
+	tb := &g.TimeBank
+	if !tb.enabled() || tb.turnStart.IsZero() {
+		return
+	}
+
+	bank := &tb.team2
+	if g.clueGiverTrack.team1 {
+		bank = &tb.team1
+	}
+
+	*bank -= g.clock.Now().Sub(tb.turnStart)
+	if *bank < 0 {
+		*bank = 0
+	}
+	tb.turnStart = time.Time{}
+	updateTimeBankLeft(g)
+}
+
+func updateTimeBankLeft(g *Game) {
+	g.TimeBank.Team1Left = int(g.TimeBank.team1.Round(time.Second) / time.Second)
+	g.TimeBank.Team2Left = int(g.TimeBank.team2.Round(time.Second) / time.Second)
+}
+
+// timeBankEmpty is whether the team doesn't have enough time left in their bank to take a turn
+func timeBankEmpty(g *Game, team1 bool) bool {
+	if !g.TimeBank.enabled() {
+		return false
+	}
+
+	if team1 {
+		return g.TimeBank.team1 < time.Second
+	}
+	return g.TimeBank.team2 < time.Second
+}
+
+// outOfTime ends the round, or the game, once both teams have emptied their time banks
+func outOfTime(g *Game) {
+	g.ClueGiver = nil
+	log.Printf("Both teams are out of time in game %s", g.Code)
+
+	if g.Round == 3 || g.TimeBank.Scope == timeBankGame {
+		g.Team1.sendNotification("Both teams are out of time")
+		g.Team2.sendNotification("Both teams are out of time")
+		endGame(g)
+		return
+	}
+
+	g.Team1.sendNotification("Both teams are out of time for this round")
+	g.Team2.sendNotification("Both teams are out of time for this round")
+	changeRound(g, g.Round+1)
+}
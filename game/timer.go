@@ -10,35 +10,42 @@ import (
 
 const timerPoll = 500 * time.Millisecond
 
-func startTimer(duration time.Duration, tick func(passed time.Duration), finish, timeout func()) chan bool {
-	stop := make(chan bool)
+// startTimer runs until the duration passes or the timer is stopped.  Tick is only for checks that can end a timer
+// early, clients count down from the timer's deadline on their own so a nil tick doesn't poll at all.  The callbacks
+// are run in order on the timer's go routine, so they should only queue their work and return.  The timer is
+// started on the clock before startTimer returns, so it always counts from when it was called
+func startTimer(clock Clock, duration time.Duration, tick func(), finish, timeout func()) chan bool {
+	stop := make(chan bool, 1) // buffered so stopping a timer that has already expired doesn't block
+
+	c := clock.After(duration)
+	var ticker Ticker
+	var poll <-chan time.Time
+	if tick != nil {
+		ticker = clock.NewTicker(timerPoll)
+		poll = ticker.C()
+	}
 
 	go func() {
-		c := time.After(duration)
-		ticker := time.NewTicker(timerPoll)
+		if ticker != nil {
+			defer ticker.Stop()
+		}
 		defer func() {
-			ticker.Stop()
 			if finish != nil {
-				go finish()
+				finish()
 			}
 		}()
 
-		last := time.Now()
-
 		for {
 			select {
 			case <-stop:
 				return
 			case <-c:
 				if timeout != nil {
-					go timeout()
+					timeout()
 				}
 				return
-			case t := <-ticker.C:
-				if tick != nil {
-					go tick(t.Sub(last))
-				}
-				last = t
+			case <-poll:
+				tick()
 			}
 		}
 	}()
@@ -0,0 +1,200 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package game
+
+import (
+	"log"
+	"strings"
+
+	"github.com/timshannon/threenamesinahat/fail"
+)
+
+// viewer roles
+const (
+	roleDisplay = "display" // big screen host display
+	roleOverlay = "overlay" // streaming overlay, such as an OBS browser source
+)
+
+// Viewer is a read only connection to a game, such as a big screen display in the room.  Viewers receive the game
+// state, but never any of the names in the hat, and aren't counted as players
+type Viewer struct {
+	Role string
+
+	Receive chan Request `json:"-"`
+
+	outbox *outbox
+
+	game *Game
+}
+
+// viewerState is the game state with everything a viewer shouldn't see removed
+type viewerState struct {
+	gameState
+	Team1     viewerTeam    `json:"team1"`
+	Team2     viewerTeam    `json:"team2"`
+	Leader    *viewerPlayer `json:"leader"`
+	ClueGiver *viewerPlayer `json:"clueGiver"`
+}
+
+type viewerTeam struct {
+	Players []viewerPlayer `json:"players"`
+}
+
+type viewerPlayer struct {
+	Name      string `json:"name"`
+	Turns     int    `json:"turns"`
+	Connected bool   `json:"connected"`
+}
+
+// Watch connects a new viewer with the given role to a game
+func Watch(req WatchRequest, ipAddress string) (*Viewer, error) {
+	g, ok := Find(req.Code)
+	if !ok {
+		return nil, fail.NotFound("Invalid Game code, try again")
+	}
+
+	role := strings.ToLower(req.Role)
+	if role != roleDisplay && role != roleOverlay {
+		return nil, fail.New("%s is an invalid viewer role", role)
+	}
+
+	v := &Viewer{
+		Role:    role,
+		outbox:  newOutbox(),
+		Receive: make(chan Request, 5),
+		game:    g,
+	}
+
+	err := passphraseAttempt(ipAddress, g.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fail.NotFound("Invalid Game code, try again")
+	g.call(func() {
+		err = checkSpectator(g, req.Passphrase)
+		if err == nil {
+			g.viewers = append(g.viewers, v)
+			sendViewerSnapshot(g, v)
+		}
+	})
+
+	err = passphraseResult(err, ipAddress, g.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	go v.recieve()
+	log.Printf("%s viewer connected to game %s", role, g.Code)
+	return v, nil
+}
+
+// Close disconnects the viewer from the game
+func (v *Viewer) Close() {
+	g := v.game
+	g.do(func() {
+		removeViewer(g, v)
+	})
+	close(v.Receive)
+}
+
+// removeViewer stops sending updates to a viewer
+func removeViewer(g *Game, v *Viewer) {
+	for i := range g.viewers {
+		if g.viewers[i] == v {
+			g.viewers = append(g.viewers[:i], g.viewers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (v *Viewer) recieve() {
+	for req := range v.Receive {
+		switch strings.ToLower(req.Type) {
+		case "requestupdate":
+			v.game.do(func() {
+				sendViewerSnapshot(v.game, v)
+			})
+		case "timesync":
+			msg, err := timeSyncMsg(req)
+			if err != nil {
+				v.SendMsg(ErrorMsg(err))
+				continue
+			}
+			v.SendMsg(msg)
+		default:
+			v.SendMsg(ErrorMsg(fail.New("Viewers can't send %s messages", req.Type)))
+		}
+	}
+}
+
+// SendMsg queues a Msg to be sent to a viewer in order
+func (v *Viewer) SendMsg(msg Msg) {
+	v.outbox.add(msg)
+}
+
+// Messages returns the messages sent to the viewer after the passed in sequence number, and a channel that is
+// closed when the next message is sent.  Returns false if the viewer fell so far behind that messages were dropped
+func (v *Viewer) Messages(after uint64) ([]Msg, <-chan struct{}, bool) {
+	return v.outbox.since(after)
+}
+
+// Written records that every message up to the passed in sequence number has been written to the viewer's
+// connection.  Viewers always start over with a new snapshot, so written messages are let go right away
+func (v *Viewer) Written(seq uint64) {
+	v.outbox.wrote(seq)
+	v.outbox.ack(seq)
+}
+
+// newViewerState builds the state sent to viewers
+func newViewerState(g *Game) viewerState {
+	return viewerState{
+		gameState: g.gameState,
+		Team1:     newViewerTeam(&g.Team1),
+		Team2:     newViewerTeam(&g.Team2),
+		Leader:    newViewerPlayer(g.Leader),
+		ClueGiver: newViewerPlayer(g.ClueGiver),
+	}
+}
+
+func newViewerTeam(t *Team) viewerTeam {
+	vt := viewerTeam{Players: make([]viewerPlayer, 0, len(t.Players))}
+	for _, p := range t.Players {
+		vt.Players = append(vt.Players, *newViewerPlayer(p))
+	}
+	return vt
+}
+
+func newViewerPlayer(p *Player) *viewerPlayer {
+	if p == nil {
+		return nil
+	}
+	return &viewerPlayer{Name: p.Name, Turns: p.turns(), Connected: p.Connected}
+}
+
+func updateViewers(g *Game) {
+	if len(g.viewers) == 0 {
+		return
+	}
+
+	if patch, ok := g.viewerStates.patch(newViewerState(g)); ok {
+		sendViewers(g, patch)
+	}
+}
+
+// sendViewerSnapshot sends the full viewer state to a single viewer
+func sendViewerSnapshot(g *Game, v *Viewer) {
+	snapshot, patch, changed := g.viewerStates.snapshot(newViewerState(g))
+	if changed {
+		sendViewers(g, patch)
+	}
+	v.SendMsg(snapshot)
+}
+
+func sendViewers(g *Game, msg Msg) {
+	for _, v := range g.viewers {
+		v.SendMsg(msg)
+	}
+}
@@ -7,6 +7,8 @@ toolchain go1.23.8
 require (
 	github.com/gorilla/websocket v1.4.2
 	github.com/shurcooL/httpgzip v0.0.0-20190720172056-320755c1c1b0
+	golang.org/x/image v0.25.0
+	rsc.io/qr v0.2.0
 )
 
 require (
@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/signal"
 
+	"github.com/timshannon/threenamesinahat/game"
 	"github.com/timshannon/threenamesinahat/server"
 )
 
@@ -18,6 +19,12 @@ var flagPort string
 
 func init() {
 	flag.StringVar(&flagPort, "port", "8080", "Port for the webserver to listen on")
+	flag.DurationVar(&game.ResultsRetention, "resultsretention", game.ResultsRetention,
+		"How long the results of a finished game can be downloaded after the game is cleaned up")
+	flag.StringVar(&server.PublicURL, "publicurl", "",
+		"Base URL players use to reach the server, used for join links. Defaults to the request's host")
+	flag.StringVar(&server.MetricsPort, "metricsport", "",
+		"Port to serve metrics on, kept separate from the public site. Metrics aren't served if not set")
 }
 
 func main() {
@@ -53,6 +53,18 @@ var attribution = []attribute{
 		Author:      "Dmitri Shuralyov",
 		LicenseType: "MIT",
 	},
+	{
+		Name:        "qr",
+		URL:         "https://github.com/rsc/qr",
+		Author:      "Russ Cox",
+		LicenseType: "BSD",
+	},
+	{
+		Name:        "Go fonts",
+		URL:         "https://go.dev/blog/go-fonts",
+		Author:      "Bigelow & Holmes",
+		LicenseType: "BSD",
+	},
 	{
 		Name:        "PaperCSS",
 		URL:         "https://www.getpapercss.com",
@@ -0,0 +1,159 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"net/http"
+
+	"github.com/timshannon/threenamesinahat/game"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// card dimensions are the recommended size for Open Graph images
+const (
+	cardWidth  = 1200
+	cardHeight = 630
+	cardMargin = 60
+	maxAwards  = 4
+)
+
+var (
+	cardBackground = color.RGBA{0xfd, 0xfb, 0xf5, 0xff}
+	cardText       = color.RGBA{0x41, 0x40, 0x3e, 0xff}
+	cardTeam1      = color.RGBA{0x41, 0x40, 0x3e, 0xff}
+	cardTeam2      = color.RGBA{0xa7, 0x34, 0x1a, 0xff}
+	cardAccent     = color.RGBA{0x0b, 0x74, 0xd5, 0xff}
+)
+
+// fonts are parsed once, but faces can't be shared between concurrent renders, so they are created for each card
+var (
+	cardBoldFont    = mustFont(gobold.TTF)
+	cardRegularFont = mustFont(goregular.TTF)
+)
+
+func mustFont(ttf []byte) *opentype.Font {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		panic(fmt.Sprintf("Error parsing card font: %s", err))
+	}
+	return f
+}
+
+func mustFace(f *opentype.Font, size float64) font.Face {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Error loading card font: %s", err))
+	}
+	return face
+}
+
+func cardPNG(w http.ResponseWriter, r *http.Request) {
+	code, _ := gamePath(r.URL.Path)
+	g, ok := game.Find(code)
+	if !ok {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	card, ok := g.Card()
+	if !ok {
+		http.Error(w, "Game has not yet ended", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	// the card changes with every game played under the same code
+	w.Header().Set("Cache-Control", "no-cache")
+	err := png.Encode(w, renderCard(card))
+	if err != nil {
+		log.Printf("Error writing card for game %s: %s", card.Code, err)
+	}
+}
+
+func renderCard(card *game.Card) image.Image {
+	titleFace := mustFace(cardBoldFont, 64)
+	scoreFace := mustFace(cardBoldFont, 96)
+	labelFace := mustFace(cardBoldFont, 28)
+	textFace := mustFace(cardRegularFont, 28)
+
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(cardBackground), image.Point{}, draw.Src)
+
+	title := "It's a tie!"
+	titleColor := cardText
+	switch card.Winner {
+	case 1:
+		title, titleColor = "Team 1 wins!", cardTeam1
+	case 2:
+		title, titleColor = "Team 2 wins!", cardTeam2
+	}
+	drawCentered(img, titleFace, titleColor, title, 110)
+
+	drawText(img, labelFace, cardTeam1, "Team 1", cardMargin, 200)
+	drawText(img, scoreFace, cardTeam1, fmt.Sprint(card.Team1Score), cardMargin, 290)
+	drawRight(img, labelFace, cardTeam2, "Team 2", cardWidth-cardMargin, 200)
+	drawRight(img, scoreFace, cardTeam2, fmt.Sprint(card.Team2Score), cardWidth-cardMargin, 290)
+
+	y := 360
+	for i, award := range card.Awards {
+		if i == maxAwards {
+			break
+		}
+		drawText(img, labelFace, cardAccent, award.Title, cardMargin, y)
+		drawText(img, textFace, cardText, truncate(textFace, award.Detail, cardWidth-cardMargin*2-360),
+			cardMargin+360, y)
+		y += 50
+	}
+
+	drawRight(img, textFace, cardText, "Three Names in a Hat - Game "+card.Code, cardWidth-cardMargin,
+		cardHeight-30)
+	return img
+}
+
+func drawText(img draw.Image, face font.Face, c color.Color, text string, x, y int) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+func drawCentered(img draw.Image, face font.Face, c color.Color, text string, y int) {
+	width := font.MeasureString(face, text).Round()
+	drawText(img, face, c, text, (cardWidth-width)/2, y)
+}
+
+func drawRight(img draw.Image, face font.Face, c color.Color, text string, right, y int) {
+	width := font.MeasureString(face, text).Round()
+	drawText(img, face, c, text, right-width, y)
+}
+
+// truncate shortens text with an ellipsis until it fits in the given width
+func truncate(face font.Face, text string, width int) string {
+	if font.MeasureString(face, text).Round() <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && font.MeasureString(face, string(runes)+"...").Round() > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
@@ -8,11 +8,19 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/timshannon/threenamesinahat/fail"
 	"github.com/timshannon/threenamesinahat/game"
 )
 
+const (
+	writeTimeout = 10 * time.Second // slow connections are dropped rather than holding up their messages
+	pingPeriod   = 5 * time.Second  // how often connections are pinged to check they're still there
+	pongWait     = 15 * time.Second // connections that haven't been heard from in this long are dropped
+)
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  512,
 	WriteBufferSize: 512,
@@ -26,13 +34,49 @@ var gameNotFound = gzipHandler(templateHandler(func(w *templateWriter, r *http.R
 	})
 }, "notfound.template.html"))
 
-func gameTemplate(w *templateWriter, r *http.Request) {
-	s := strings.Split(r.URL.Path, "/")
-	code := s[len(s)-1]
-	// handle trailing slash
-	if code == "" {
-		code = s[len(s)-2]
+var gamePage = gzipHandler(templateHandler(gameTemplate, "game.template.html"))
+var displayPage = gzipHandler(templateHandler(gameTemplate, "display.template.html"))
+var overlayPage = gzipHandler(templateHandler(gameTemplate, "overlay.template.html"))
+
+// gamePath splits a game url into the game code and the game resource being requested
+// i.e. /game/ABCD/results.json returns ABCD and results.json
+func gamePath(path string) (code, resource string) {
+	s := strings.SplitN(strings.Trim(strings.TrimPrefix(path, "/game/"), "/"), "/", 2)
+	code = s[0]
+	if len(s) > 1 {
+		resource = s[1]
+	}
+	return code, resource
+}
+
+// gameRoutes routes all requests for a specific game
+func gameRoutes(w http.ResponseWriter, r *http.Request) {
+	_, resource := gamePath(r.URL.Path)
+
+	switch resource {
+	case "":
+		gamePage(w, r)
+	case "results.json":
+		gzipHandler(resultsJSON)(w, r)
+	case "results.csv":
+		gzipHandler(resultsCSV)(w, r)
+	case "display":
+		displayPage(w, r)
+	case "overlay":
+		overlayPage(w, r)
+	case "qr.png":
+		qrPNG(w, r)
+	case "qr.svg":
+		qrSVG(w, r)
+	case "card.png":
+		cardPNG(w, r)
+	default:
+		notFound(w, r)
 	}
+}
+
+func gameTemplate(w *templateWriter, r *http.Request) {
+	code, _ := gamePath(r.URL.Path)
 
 	g, ok := game.Find(code)
 
@@ -40,7 +84,17 @@ func gameTemplate(w *templateWriter, r *http.Request) {
 		gameNotFound(w, r)
 		return
 	}
-	w.execute(g)
+
+	card, _ := g.Card()
+	w.execute(struct {
+		*game.Game
+		URL  string
+		Card *game.Card // set once the game has ended, for link previews
+	}{
+		Game: g,
+		URL:  baseURL(r) + "/game/" + g.Code,
+		Card: card,
+	})
 }
 
 func gameSocket(w http.ResponseWriter, r *http.Request) {
@@ -50,54 +104,210 @@ func gameSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	m := &game.Msg{}
-	err = websocket.ReadJSON(ws, m)
+	req, err := readRequest(ws)
 	if err != nil {
-		websocket.WriteJSON(ws, &game.Msg{Type: "error", Data: err.Error()})
+		if fail.IsFailure(err) {
+			closeWithError(ws, err)
+		}
+		ws.Close()
+		return
 	}
 
-	if strings.ToLower(m.Type) != "join" {
-		ws.Close()
+	switch strings.ToLower(req.Type) {
+	case "join":
+		joinSocket(ws, r, req)
+	case "watch":
+		watchSocket(ws, r, req)
+	default:
+		closeWithError(ws, fail.New("The first message must be a join or watch, got %s", req.Type))
+	}
+}
+
+func joinSocket(ws *websocket.Conn, r *http.Request, req game.Request) {
+	var join game.JoinRequest
+	err := req.DecodeData(&join)
+	if err != nil {
+		closeWithError(ws, err)
 		return
 	}
 
-	data, ok := m.Data.(map[string]interface{})
-	if !ok {
-		websocket.WriteJSON(ws, &game.Msg{Type: "error", Data: "Invalid websocket data"})
+	version, err := game.NegotiateVersion(join.Version)
+	if err != nil {
+		closeWithError(ws, err)
+		return
+	}
+
+	player, last, err := game.Join(join, ipAddress(r))
+	if err != nil {
+		closeWithError(ws, err)
+		return
+	}
+	alive := keepAlive(ws, player.Seen)
+	player.Connect()
+	defer player.Disconnect()
+
+	err = websocket.WriteJSON(ws, game.NewWelcome(version))
+	if err != nil {
 		ws.Close()
 		return
 	}
 
-	gameCode := data["code"].(string)
-	playerName := data["name"].(string)
+	done := make(chan struct{})
+	defer close(done)
+
+	go writeMessages(ws, player, last, done, "player "+join.Name+" in game "+join.Code)
+
+	for {
+		req, err = readRequest(ws)
+		if err != nil {
+			if !fail.IsFailure(err) {
+				log.Printf("Error recieving on web socket: %s", err)
+				ws.Close()
+				return
+			}
+			// malformed messages are rejected, but don't drop the connection
+			alive()
+			player.SendMsg(game.ErrorMsg(err))
+			continue
+		}
+		alive()
+
+		select {
+		case player.Receive <- req:
+		case <-player.Done():
+			ws.Close()
+			return
+		}
+	}
+}
+
+// watchSocket connects a read only viewer, such as a big screen display, to the game
+func watchSocket(ws *websocket.Conn, r *http.Request, req game.Request) {
+	var watch game.WatchRequest
+	err := req.DecodeData(&watch)
+	if err != nil {
+		closeWithError(ws, err)
+		return
+	}
+
+	version, err := game.NegotiateVersion(watch.Version)
+	if err != nil {
+		closeWithError(ws, err)
+		return
+	}
 
-	player, err := game.Join(gameCode, playerName)
+	viewer, err := game.Watch(watch, ipAddress(r))
+	if err != nil {
+		closeWithError(ws, err)
+		return
+	}
+	defer viewer.Close()
+	alive := keepAlive(ws, func() {})
 
+	err = websocket.WriteJSON(ws, game.NewWelcome(version))
 	if err != nil {
-		websocket.WriteJSON(ws, &game.Msg{Type: "error", Data: err.Error()})
 		ws.Close()
 		return
 	}
 
-	go func() {
-		for msg := range player.Send {
-			err := websocket.WriteJSON(ws, msg)
-			if err != nil {
-				log.Printf("Error in game %s sending to player %s: %s", gameCode, playerName, err)
+	done := make(chan struct{})
+	defer close(done)
+
+	go writeMessages(ws, viewer, 0, done, watch.Role+" viewer of game "+watch.Code)
+
+	for {
+		req, err = readRequest(ws)
+		if err != nil {
+			if !fail.IsFailure(err) {
 				ws.Close()
 				return
 			}
+			alive()
+			viewer.SendMsg(game.ErrorMsg(err))
+			continue
 		}
-	}()
+		alive()
+
+		viewer.Receive <- req
+	}
+}
+
+// outbox is where a connection's messages are queued, see Player and Viewer
+type outbox interface {
+	Messages(after uint64) ([]game.Msg, <-chan struct{}, bool)
+	Written(seq uint64)
+}
+
+// keepAlive drops the connection if the client isn't heard from within the pong wait, calling seen every time
+// they are.  Browsers answer the pings sent by writeMessages on their own, and the returned func should be called
+// whenever a message is read
+func keepAlive(ws *websocket.Conn, seen func()) func() {
+	alive := func() {
+		ws.SetReadDeadline(time.Now().Add(pongWait))
+		seen()
+	}
+	ws.SetPongHandler(func(string) error {
+		alive()
+		return nil
+	})
+	alive()
+	return alive
+}
+
+// writeMessages writes everything queued in the outbox after the last sequence number to the websocket, in order,
+// and pings the client, until the connection is done
+func writeMessages(ws *websocket.Conn, out outbox, last uint64, done <-chan struct{}, who string) {
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
 
 	for {
-		err = websocket.ReadJSON(ws, m)
-		if err != nil {
-			log.Printf("Error recieving on web socket: %s", err)
+		msgs, sent, ok := out.Messages(last)
+		if !ok {
+			// the client fell too far behind, reconnecting will get them the full game state again
+			log.Printf("Dropping connection to %s, too many messages were missed", who)
 			ws.Close()
 			return
 		}
+		for _, msg := range msgs {
+			ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err := websocket.WriteJSON(ws, msg)
+			if err != nil {
+				log.Printf("Error sending to %s: %s", who, err)
+				ws.Close()
+				return
+			}
+			last = msg.Seq
+		}
+		if len(msgs) > 0 {
+			out.Written(last)
+		}
 
-		player.Receive <- *m
+		select {
+		case <-done:
+			return
+		case <-sent:
+		case <-ping.C:
+			err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+			if err != nil {
+				log.Printf("Error pinging %s: %s", who, err)
+				ws.Close()
+				return
+			}
+		}
 	}
 }
+
+// readRequest reads the next message from the websocket, failures are malformed messages while any other error
+// means the connection is gone
+func readRequest(ws *websocket.Conn) (game.Request, error) {
+	_, reader, err := ws.NextReader()
+	if err != nil {
+		return game.Request{}, err
+	}
+	return game.ReadRequest(reader)
+}
+
+func closeWithError(ws *websocket.Conn, err error) {
+	websocket.WriteJSON(ws, game.ErrorMsg(err))
+	ws.Close()
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/timshannon/threenamesinahat/game"
+)
+
+// MetricsPort is the port metrics are served on.  Metrics are only served when it's set, on their own listener so
+// they're never reachable from the public site
+var MetricsPort string
+
+type metrics struct {
+	DroppedMessages int64 `json:"droppedMessages"`
+}
+
+func startMetrics() {
+	if MetricsPort == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(metrics{
+			DroppedMessages: game.DroppedMessages(),
+		})
+		if err != nil {
+			log.Printf("Error writing metrics: %s", err)
+		}
+	})
+
+	go func() {
+		log.Printf("Serving metrics on port %s", MetricsPort)
+		err := http.ListenAndServe(":"+MetricsPort, mux)
+		if err != nil {
+			log.Printf("Metrics server error: %s", err)
+		}
+	}()
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/timshannon/threenamesinahat/game"
+	"rsc.io/qr"
+)
+
+// PublicURL is the base URL players use to reach the server, such as https://example.com.  If it isn't set, the
+// join URL is built from the host of the request
+var PublicURL = ""
+
+const (
+	qrScale     = 8 // image pixels per QR module
+	qrQuietZone = 4 // modules of white border required around a QR code
+)
+
+func qrPNG(w http.ResponseWriter, r *http.Request) {
+	code, ok := joinQR(w, r)
+	if !ok {
+		return
+	}
+
+	size := (code.Size + qrQuietZone*2) * qrScale
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := color.Gray{Y: 0xFF}
+			if code.Black(x/qrScale-qrQuietZone, y/qrScale-qrQuietZone) {
+				c = color.Gray{Y: 0x00}
+			}
+			img.SetGray(x, y, c)
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	err := png.Encode(w, img)
+	if err != nil {
+		log.Printf("Error writing QR code png: %s", err)
+	}
+}
+
+func qrSVG(w http.ResponseWriter, r *http.Request) {
+	code, ok := joinQR(w, r)
+	if !ok {
+		return
+	}
+
+	size := code.Size + qrQuietZone*2
+	var path strings.Builder
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+qrQuietZone, y+qrQuietZone)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`, size, size, path.String())
+	if err != nil {
+		log.Printf("Error writing QR code svg: %s", err)
+	}
+}
+
+// joinQR encodes the join URL for the requested game, writing a 404 if the game doesn't exist
+func joinQR(w http.ResponseWriter, r *http.Request) (*qr.Code, bool) {
+	code, _ := gamePath(r.URL.Path)
+	g, ok := game.Find(code)
+	if !ok {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	qrCode, err := qr.Encode(baseURL(r)+"/game/"+g.Code, qr.M)
+	if err != nil {
+		log.Printf("Error encoding QR code for game %s: %s", g.Code, err)
+		http.Error(w, "Error generating QR code", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return qrCode, true
+}
+
+// baseURL is the absolute URL of the server, either the configured PublicURL or built from the request
+func baseURL(r *http.Request) string {
+	if PublicURL != "" {
+		return strings.TrimSuffix(PublicURL, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+
+	return scheme + "://" + r.Host
+}
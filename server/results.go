@@ -0,0 +1,45 @@
+// Copyright 2020 Tim Shannon. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/timshannon/threenamesinahat/game"
+)
+
+func resultsJSON(w http.ResponseWriter, r *http.Request) {
+	code, _ := gamePath(r.URL.Path)
+	results, ok := game.FindResults(code)
+	if !ok {
+		notFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+results.Code+`-results.json"`)
+	err := json.NewEncoder(w).Encode(results)
+	if err != nil {
+		log.Printf("Error writing JSON results for game %s: %s", results.Code, err)
+	}
+}
+
+func resultsCSV(w http.ResponseWriter, r *http.Request) {
+	code, _ := gamePath(r.URL.Path)
+	results, ok := game.FindResults(code)
+	if !ok {
+		notFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+results.Code+`-results.csv"`)
+	err := results.WriteCSV(w)
+	if err != nil {
+		log.Printf("Error writing CSV results for game %s: %s", results.Code, err)
+	}
+}
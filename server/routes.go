@@ -27,7 +27,7 @@ func setupRoutes() {
 
 		http.Redirect(w, r, "/game/"+g.Code, http.StatusTemporaryRedirect)
 	}))
-	get("/game/", gzipHandler(templateHandler(gameTemplate, "game.template.html")))
+	get("/game/", gameRoutes)
 	http.HandleFunc("/game", gameSocket)
 	get("/join", gzipHandler(templateHandler(emptyTemplate, "join.template.html")))
 	get("/about", gzipHandler(templateHandler(aboutTemplate, "about.template.html")))
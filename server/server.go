@@ -29,6 +29,7 @@ func init() {
 
 func Start(port string, shutdown chan bool) error {
 	setupRoutes()
+	startMetrics()
 	server = &http.Server{
 		Addr: ":" + port,
 	}